@@ -8,15 +8,18 @@ import (
 	"github.com/go-chi/cors"
 	"github.com/lucianboboc/goBackendEngineering/docs"
 	"github.com/lucianboboc/goBackendEngineering/internal/auth"
+	"github.com/lucianboboc/goBackendEngineering/internal/blob"
 	"github.com/lucianboboc/goBackendEngineering/internal/mailer"
 	"github.com/lucianboboc/goBackendEngineering/internal/ratelimiter"
 	"github.com/lucianboboc/goBackendEngineering/internal/store"
 	"github.com/lucianboboc/goBackendEngineering/internal/store/cache"
+	"github.com/lucianboboc/goBackendEngineering/internal/views"
 	httpSwagger "github.com/swaggo/http-swagger"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
@@ -32,6 +35,8 @@ type application struct {
 	mailer        mailer.Client
 	authenticator auth.Authenticator
 	rateLimiter   ratelimiter.Limiter
+	blobStore     blob.Store
+	views         *views.Counter
 }
 
 type config struct {
@@ -44,6 +49,43 @@ type config struct {
 	auth        authConfig
 	redisCfg    redisConfig
 	ratelimiter ratelimiter.Config
+	retention   retentionConfig
+	uploads     uploadsConfig
+	publisher   publisherConfig
+	pins        pinsConfig
+	trending    trendingConfig
+	views       viewsConfig
+}
+
+type retentionConfig struct {
+	deletedContent time.Duration
+	interval       time.Duration
+}
+
+type publisherConfig struct {
+	interval  time.Duration
+	batchSize int
+}
+
+type trendingConfig struct {
+	window   time.Duration
+	interval time.Duration
+	size     int
+}
+
+type viewsConfig struct {
+	window        time.Duration
+	flushInterval time.Duration
+}
+
+type pinsConfig struct {
+	limit int
+}
+
+type uploadsConfig struct {
+	dir         string
+	maxFileSize int64
+	maxFiles    int
 }
 
 type redisConfig struct {
@@ -98,9 +140,9 @@ func (app *application) mount() http.Handler {
 		// AllowedOrigins:   []string{"https://foo.com"}, // Use this to allow specific origin hosts
 		AllowedOrigins: []string{"https://*", "http://*"},
 		// AllowOriginFunc:  func(r *http.Request, origin string) bool { return true },
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "If-Match", "If-None-Match"},
+		ExposedHeaders:   []string{"Link", "ETag"},
 		AllowCredentials: false,
 		MaxAge:           300, // Maximum value not ignored by any of major browsers
 	}))
@@ -129,23 +171,75 @@ func (app *application) mount() http.Handler {
 			r.Post("/", app.createPostsHandler)
 
 			r.Route("/{post_id}", func(r chi.Router) {
-				r.Use(app.postsContextMiddleware)
-				r.Get("/", app.getPostHandler)
-				r.Patch("/", app.checkPostOwnership("moderator", app.updatePostHandler))
-				r.Delete("/", app.checkPostOwnership("admin", app.deletePostHandler))
-
-				r.Get("/comments", app.getCommentsByPost)
-				r.Post("/comments", app.createPostComment)
+				// deleted posts are invisible to postsContextMiddleware
+				r.Put("/restore", app.checkRole("admin", app.restorePostHandler))
+
+				// moderators reach the posts hidden from them only to moderate them
+				r.Group(func(r chi.Router) {
+					r.Use(app.moderatedPostsContextMiddleware)
+					r.Patch("/", app.checkPostOwnership("moderator", app.updatePostHandler))
+					r.Delete("/", app.checkPostOwnership("admin", app.deletePostHandler))
+					r.Post("/revisions/{version}/revert", app.checkPostOwnership("moderator", app.revertPostHandler))
+					r.Put("/comments/lock", app.checkPostOwnership("moderator", app.lockCommentsHandler))
+					r.Delete("/comments/lock", app.checkPostOwnership("moderator", app.unlockCommentsHandler))
+
+					r.With(app.commentsContextMiddleware).Patch("/comments/{comment_id}", app.checkCommentAuthor(app.updateCommentHandler))
+					r.With(app.commentsContextMiddleware).Delete("/comments/{comment_id}", app.checkCommentOwnership("moderator", app.deleteCommentHandler))
+				})
+
+				r.Group(func(r chi.Router) {
+					r.Use(app.postsContextMiddleware)
+					r.Get("/", app.getPostHandler)
+
+					r.Route("/revisions", func(r chi.Router) {
+						r.Get("/", app.getPostRevisionsHandler)
+						r.Get("/diff", app.getPostRevisionsDiffHandler)
+					})
+
+					r.Route("/reactions", func(r chi.Router) {
+						r.Get("/", app.getPostReactionsHandler)
+						r.Put("/{type}", app.reactPostHandler)
+						r.Delete("/{type}", app.unreactPostHandler)
+					})
+
+					r.Put("/bookmark", app.bookmarkPostHandler)
+					r.Delete("/bookmark", app.unbookmarkPostHandler)
+
+					r.Put("/repost", app.repostHandler)
+					r.Delete("/repost", app.unrepostHandler)
+
+					r.Get("/views", app.checkPostOwnership("admin", app.getPostViewsHandler))
+
+					r.Put("/pin", app.checkPostOwnership("admin", app.pinPostHandler))
+					r.Delete("/pin", app.checkPostOwnership("admin", app.unpinPostHandler))
+
+					r.Get("/comments", app.getCommentsByPost)
+					r.Post("/comments", app.createPostComment)
+
+					r.Route("/comments/{comment_id}/replies", func(r chi.Router) {
+						r.Use(app.commentsContextMiddleware)
+						r.Get("/", app.getCommentRepliesHandler)
+						r.Post("/", app.createCommentReplyHandler)
+					})
+				})
 			})
 		})
 
 		r.Route("/users", func(r chi.Router) {
 			r.Put("/activate/{token}", app.activateUserHandler)
 
+			r.Route("/me", func(r chi.Router) {
+				r.Use(app.AuthTokenMiddleware)
+				r.Get("/bookmarks", app.getBookmarksHandler)
+				r.Get("/mentions", app.getMentionsHandler)
+				r.Get("/drafts", app.getDraftsHandler)
+			})
+
 			r.Route("/{user_id}", func(r chi.Router) {
 				r.Use(app.AuthTokenMiddleware)
 
 				r.Get("/", app.getUserHandler)
+				r.Get("/posts", app.getUserPostsHandler)
 				r.Patch("/", app.updateUserHandler)
 				r.Delete("/", app.deleteUserHandler)
 
@@ -159,6 +253,21 @@ func (app *application) mount() http.Handler {
 			})
 		})
 
+		r.Route("/tags", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/", app.autocompleteTagsHandler)
+			r.Get("/{tag}", app.getTagHandler)
+		})
+
+		r.Route("/trending", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/posts", app.getTrendingPostsHandler)
+			r.Get("/tags", app.getTrendingTagsHandler)
+		})
+
+		r.With(app.AuthTokenMiddleware).Get("/search", app.searchHandler)
+		r.With(app.AuthTokenMiddleware).Get("/attachments/{attachment_id}", app.getAttachmentHandler)
+
 		r.Route("/authentication", func(r chi.Router) {
 			r.Post("/user", app.registerUserHandler)
 
@@ -196,6 +305,19 @@ func (app *application) run(mux http.Handler) error {
 		shutdown <- srv.Shutdown(ctx)
 	}()
 
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	var jobs sync.WaitGroup
+	app.startJobs(jobsCtx, &jobs)
+	defer func() {
+		stopJobs()
+		jobs.Wait()
+
+		// the views counted since the last flush would be lost otherwise
+		if err := app.flushViews(context.Background()); err != nil {
+			app.logger.Error("failed to flush post views", slog.Any("error", err.Error()))
+		}
+	}()
+
 	app.logger.Info("server has started", "Addr", app.config.addr)
 
 	err := srv.ListenAndServe()
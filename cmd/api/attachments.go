@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/lucianboboc/goBackendEngineering/internal/blob"
+	"github.com/lucianboboc/goBackendEngineering/internal/store"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// allowedAttachmentTypes are the content types accepted for uploads. The type
+// is sniffed from the file itself, the one sent by the client is ignored.
+var allowedAttachmentTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"video/mp4":       true,
+	"application/pdf": true,
+}
+
+// multipartOverhead is the room left for the form fields and part headers.
+const multipartOverhead = 1 << 20
+
+var (
+	errAttachmentTooLarge        = errors.New("attachment is too large")
+	errTooManyAttachments        = errors.New("too many attachments")
+	errUnsupportedAttachmentType = errors.New("unsupported attachment type")
+)
+
+// isMultipartForm reports whether the request body is multipart/form-data.
+func isMultipartForm(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "multipart/form-data"
+}
+
+// readPostForm reads a post sent as multipart/form-data. The fields match
+// CreatePostPayload, tags are sent as repeated fields and the files as
+// "attachments". The caller has to call r.MultipartForm.RemoveAll.
+func (app *application) readPostForm(w http.ResponseWriter, r *http.Request, payload *CreatePostPayload) ([]*multipart.FileHeader, error) {
+	maxBytes := app.config.uploads.maxFileSize*int64(app.config.uploads.maxFiles) + multipartOverhead
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		return nil, err
+	}
+
+	payload.Title = r.PostFormValue("title")
+	payload.Content = r.PostFormValue("content")
+	payload.Tags = r.PostForm["tags"]
+	payload.Visibility = r.PostFormValue("visibility")
+	payload.Status = r.PostFormValue("status")
+
+	if v := r.PostFormValue("publish_at"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid publish_at: %w", err)
+		}
+		payload.PublishAt = &t
+	}
+
+	if v := r.PostFormValue("quoted_post_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quoted_post_id: %w", err)
+		}
+		payload.QuotedPostID = &id
+	}
+
+	files := r.MultipartForm.File["attachments"]
+	if len(files) > app.config.uploads.maxFiles {
+		return nil, errTooManyAttachments
+	}
+
+	for _, fh := range files {
+		if fh.Size > app.config.uploads.maxFileSize {
+			return nil, fmt.Errorf("%w: %s is larger than %d bytes", errAttachmentTooLarge, fh.Filename, app.config.uploads.maxFileSize)
+		}
+	}
+
+	return files, nil
+}
+
+// storeAttachments writes the uploaded files to the blob store and returns
+// their metadata. On failure the blobs written so far are removed again.
+func (app *application) storeAttachments(ctx context.Context, files []*multipart.FileHeader) ([]store.Attachment, error) {
+	attachments := make([]store.Attachment, 0, len(files))
+	for _, fh := range files {
+		attachment, err := app.storeAttachment(ctx, fh)
+		if err != nil {
+			app.deleteAttachmentBlobs(ctx, attachments)
+			return nil, err
+		}
+		attachments = append(attachments, *attachment)
+	}
+
+	return attachments, nil
+}
+
+func (app *application) storeAttachment(ctx context.Context, fh *multipart.FileHeader) (*store.Attachment, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// http.DetectContentType looks at most at the first 512 bytes
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	head = head[:n]
+
+	contentType, _, err := mime.ParseMediaType(http.DetectContentType(head))
+	if err != nil || !allowedAttachmentTypes[contentType] {
+		return nil, fmt.Errorf("%w: %s", errUnsupportedAttachmentType, fh.Filename)
+	}
+
+	hash := sha256.New()
+	key := "attachments/" + uuid.NewString()
+	body := io.TeeReader(io.MultiReader(bytes.NewReader(head), f), hash)
+	if err = app.blobStore.Put(ctx, key, body); err != nil {
+		return nil, err
+	}
+
+	return &store.Attachment{
+		Key:         key,
+		Filename:    attachmentFilename(fh.Filename),
+		ContentType: contentType,
+		Size:        fh.Size,
+		Checksum:    hex.EncodeToString(hash.Sum(nil)),
+	}, nil
+}
+
+func (app *application) deleteAttachmentBlobs(ctx context.Context, attachments []store.Attachment) {
+	for _, a := range attachments {
+		if err := app.blobStore.Delete(ctx, a.Key); err != nil {
+			app.logger.Error("failed to delete attachment blob", slog.String("key", a.Key), slog.Any("error", err.Error()))
+		}
+	}
+}
+
+// attachmentFilename keeps the base name of the uploaded file, cut to fit
+// the attachments table.
+func attachmentFilename(name string) string {
+	name = filepath.Base(filepath.Clean("/" + name))
+	runes := []rune(name)
+	if len(runes) > 255 {
+		runes = runes[:255]
+	}
+	return string(runes)
+}
+
+// getAttachmentHandler godoc
+//
+//	@Summary		Downloads an attachment
+//	@Description	Streams the file of an attachment. Supports If-None-Match with the returned ETag
+//	@Tags			posts
+//	@Produce		octet-stream
+//	@Param			id				path		int		true	"Attachment ID"
+//	@Param			If-None-Match	header		string	false	"ETag of a cached copy"
+//	@Success		200				{file}		file
+//	@Success		304				{string}	string	"Not modified"
+//	@Failure		404				{object}	error
+//	@Failure		500				{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/attachments/{id} [get]
+func (app *application) getAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "attachment_id"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := getUserFromCtx(r)
+	attachment, err := app.store.Attachments.GetByID(r.Context(), id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	// the checksum identifies the content, attachments are never modified
+	etag := `"` + attachment.Checksum + `"`
+	w.Header().Set("ETag", etag)
+	if ifNoneMatch(r, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	rc, err := app.blobStore.Get(r.Context(), attachment.Key)
+	if err != nil {
+		switch {
+		case errors.Is(err, blob.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	defer rc.Close()
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": attachment.Filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err = io.Copy(w, rc); err != nil {
+		app.logger.Error("failed to stream attachment", slog.Int64("id", attachment.ID), slog.Any("error", err.Error()))
+	}
+}
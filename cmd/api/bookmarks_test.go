@@ -0,0 +1,49 @@
+package main
+
+import (
+	"github.com/lucianboboc/goBackendEngineering/internal/store"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBookmarkPost(t *testing.T) {
+	app := newTestApplication(t)
+	app.store.Posts = &versionedPostStore{post: store.Post{ID: 5, UserID: 2}}
+	mux := app.mount()
+
+	testToken, _ := app.authenticator.GenerateToken(1, "", "", time.Hour)
+
+	t.Run("should bookmark the post", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPut, "/v1/posts/5/bookmark", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusNoContent, rr.Code)
+	})
+
+	t.Run("should list the bookmarks", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/users/me/bookmarks", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("should not allow an invalid limit", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/users/me/bookmarks?limit=0", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+}
@@ -1,25 +1,86 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
 	"github.com/lucianboboc/goBackendEngineering/internal/store"
 	"net/http"
+	"strconv"
 )
 
+type commentKey string
+
+const commentCtx commentKey = "comment"
+
 type CommentPayload struct {
-	UserID  int64  `json:"user_id" validate:"required"`
 	Content string `json:"content" validate:"required,max=100"`
 }
 
+type UpdateCommentPayload struct {
+	Content string `json:"content" validate:"required,max=100"`
+}
+
+type LockCommentsPayload struct {
+	Reason string `json:"reason" validate:"required,max=300"`
+}
+
+// getCommentsByPost godoc
+//
+//	@Summary		Fetches the comments of a post
+//	@Description	Fetches a page of the top level comments of a post, newest first by default, with their replies nested oldest first. The total counts the top level comments
+//	@Tags			comments
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int		true	"Post ID"
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			cursor	query		string	false	"Cursor returned as next_cursor by the previous page"
+//	@Param			sort	query		string	false	"Sort by creation date: newest or oldest"
+//	@Success		200		{object}	[]store.Comment
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/comments [get]
 func (app *application) getCommentsByPost(w http.ResponseWriter, r *http.Request) {
 	post := getPostFromCtx(r)
 
-	comments, err := app.store.Comments.GetByPostID(r.Context(), post.ID)
+	q := store.CommentsQuery{
+		Limit: 20,
+		Sort:  "newest",
+	}
+
+	q, err := q.Parse(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err = Validate.Struct(q); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	comments, err := app.store.Comments.GetByPostID(r.Context(), post.ID, q)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
-	err = app.jsonResponse(w, http.StatusOK, comments)
+	total, err := app.store.Comments.Count(r.Context(), post.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	var next *store.Cursor
+	if len(comments) == q.Limit {
+		last := comments[len(comments)-1]
+		next = &store.Cursor{CreatedAt: *last.CreatedAt, ID: last.ID}
+	}
+
+	err = app.countedJSONResponse(w, r, http.StatusOK, comments, next, total)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
@@ -27,7 +88,156 @@ func (app *application) getCommentsByPost(w http.ResponseWriter, r *http.Request
 }
 
 func (app *application) createPostComment(w http.ResponseWriter, r *http.Request) {
+	app.createComment(w, r, nil)
+}
+
+// getCommentRepliesHandler godoc
+//
+//	@Summary		Fetches the replies to a comment
+//	@Description	Fetches the replies to a comment as a tree, oldest first. Replies nested too deep to be returned with the post comments are loaded here
+//	@Tags			comments
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int	true	"Post ID"
+//	@Param			comment_id	path		int	true	"Comment ID"
+//	@Success		200			{object}	[]store.Comment
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/comments/{comment_id}/replies [get]
+func (app *application) getCommentRepliesHandler(w http.ResponseWriter, r *http.Request) {
+	comment := getCommentFromCtx(r)
+
+	replies, err := app.store.Comments.GetReplies(r.Context(), comment.PostID, comment.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err = app.jsonResponse(w, http.StatusOK, replies); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// createCommentReplyHandler godoc
+//
+//	@Summary		Replies to a comment
+//	@Description	Creates a comment replying to another comment of the post
+//	@Tags			comments
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int				true	"Post ID"
+//	@Param			comment_id	path		int				true	"Comment ID"
+//	@Param			payload		body		CommentPayload	true	"Comment payload"
+//	@Success		201			{object}	store.Comment
+//	@Failure		400			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		423			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/comments/{comment_id}/replies [post]
+func (app *application) createCommentReplyHandler(w http.ResponseWriter, r *http.Request) {
+	comment := getCommentFromCtx(r)
+	app.createComment(w, r, &comment.ID)
+}
+
+// updateCommentHandler godoc
+//
+//	@Summary		Updates a comment
+//	@Description	Updates the content of a comment. Only its author can edit it, edited comments have an edited_at time
+//	@Tags			comments
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int						true	"Post ID"
+//	@Param			comment_id	path		int						true	"Comment ID"
+//	@Param			payload		body		UpdateCommentPayload	true	"Comment payload"
+//	@Success		200			{object}	store.Comment
+//	@Failure		400			{object}	error
+//	@Failure		403			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/comments/{comment_id} [patch]
+func (app *application) updateCommentHandler(w http.ResponseWriter, r *http.Request) {
+	comment := getCommentFromCtx(r)
+
+	var payload UpdateCommentPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	comment.Content = payload.Content
+	err := app.store.Comments.Update(r.Context(), comment)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err = app.jsonResponse(w, http.StatusOK, comment); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// deleteCommentHandler godoc
+//
+//	@Summary		Deletes a comment
+//	@Description	Deletes a comment and hides its replies. Comments can be deleted by their author, by the author of the post and by moderators
+//	@Tags			comments
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int		true	"Post ID"
+//	@Param			comment_id	path		int		true	"Comment ID"
+//	@Success		204			{string}	string	"Comment deleted"
+//	@Failure		403			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/comments/{comment_id} [delete]
+func (app *application) deleteCommentHandler(w http.ResponseWriter, r *http.Request) {
+	comment := getCommentFromCtx(r)
+	user := getUserFromCtx(r)
+
+	err := app.store.Comments.Delete(r.Context(), comment.ID, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err = app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// createComment adds a comment by the authenticated user to the post of the
+// request, as a reply to parentID when it is set.
+func (app *application) createComment(w http.ResponseWriter, r *http.Request, parentID *int64) {
 	post := getPostFromCtx(r)
+	user := getUserFromCtx(r)
+
+	if post.CommentsLocked {
+		err := errors.New("comments are locked on this post")
+		if post.LockReason != nil {
+			err = fmt.Errorf("%w: %s", err, *post.LockReason)
+		}
+		app.lockedResponse(w, r, err)
+		return
+	}
 
 	var payload CommentPayload
 	err := readJSON(w, r, &payload)
@@ -42,18 +252,139 @@ func (app *application) createPostComment(w http.ResponseWriter, r *http.Request
 	}
 
 	comment := &store.Comment{
-		PostID:  post.ID,
-		UserID:  payload.UserID,
-		Content: payload.Content,
+		PostID:   post.ID,
+		ParentID: parentID,
+		UserID:   user.ID,
+		Content:  payload.Content,
 	}
 	err = app.store.Comments.Create(r.Context(), comment)
 	if err != nil {
-		app.internalServerError(w, r, err)
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
 		return
 	}
+	comment.User = store.User{ID: user.ID, Username: user.Username}
 
 	err = app.jsonResponse(w, http.StatusCreated, comment)
 	if err != nil {
 		app.internalServerError(w, r, err)
 	}
 }
+
+// lockCommentsHandler godoc
+//
+//	@Summary		Locks the comments of a post
+//	@Description	Stops new comments and replies on a post. The post author and moderators can lock the comments, the reason is shown with the post
+//	@Tags			comments
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int					true	"Post ID"
+//	@Param			payload	body		LockCommentsPayload	true	"Lock payload"
+//	@Success		200		{object}	store.Post
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/comments/lock [put]
+func (app *application) lockCommentsHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromCtx(r)
+	user := getUserFromCtx(r)
+
+	var payload LockCommentsPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	err := app.store.Posts.LockComments(r.Context(), post, user.ID, payload.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err = app.jsonResponse(w, http.StatusOK, post); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// unlockCommentsHandler godoc
+//
+//	@Summary		Unlocks the comments of a post
+//	@Description	Allows new comments and replies on a post again
+//	@Tags			comments
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"Post ID"
+//	@Success		200	{object}	store.Post
+//	@Failure		403	{object}	error
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/comments/lock [delete]
+func (app *application) unlockCommentsHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromCtx(r)
+
+	err := app.store.Posts.UnlockComments(r.Context(), post)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err = app.jsonResponse(w, http.StatusOK, post); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// commentsContextMiddleware loads the comment of the URL, which has to belong
+// to the post loaded by postsContextMiddleware.
+func (app *application) commentsContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		commentID, err := strconv.ParseInt(chi.URLParam(r, "comment_id"), 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		post := getPostFromCtx(r)
+		comment, err := app.store.Comments.GetByID(r.Context(), commentID)
+		if err == nil && comment.PostID != post.ID {
+			err = store.ErrNotFound
+		}
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFoundResponse(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), commentCtx, comment)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getCommentFromCtx(r *http.Request) *store.Comment {
+	return r.Context().Value(commentCtx).(*store.Comment)
+}
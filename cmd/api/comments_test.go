@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/lucianboboc/goBackendEngineering/internal/store"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// singleCommentStore serves comment 3 of post 5, written by user 2.
+type singleCommentStore struct {
+	store.MockCommentStore
+}
+
+func (s *singleCommentStore) GetByID(ctx context.Context, id int64) (*store.Comment, error) {
+	if id != 3 {
+		return nil, store.ErrNotFound
+	}
+	return &store.Comment{ID: 3, PostID: 5, UserID: 2}, nil
+}
+
+type levelRoleStore struct{}
+
+func (s *levelRoleStore) GetByName(ctx context.Context, role string) (*store.Role, error) {
+	levels := map[string]int64{"user": 1, "moderator": 2, "admin": 3}
+	return &store.Role{Name: role, Level: levels[role]}, nil
+}
+
+// roleUserStore gives the users listed in levels the role of that level.
+type roleUserStore struct {
+	store.MockUserStore
+	levels map[int64]int64
+}
+
+func (s *roleUserStore) GetUserByID(ctx context.Context, userID int64) (*store.User, error) {
+	return &store.User{ID: userID, Role: store.Role{Level: s.levels[userID]}}, nil
+}
+
+func TestCommentOwnership(t *testing.T) {
+	app := newTestApplication(t)
+	app.store.Posts = &versionedPostStore{post: store.Post{ID: 5, UserID: 1, Status: store.StatusPublished}}
+	app.store.Comments = &singleCommentStore{}
+	app.store.Roles = &levelRoleStore{}
+	// user 6 is an admin
+	app.store.Users = &roleUserStore{levels: map[int64]int64{6: 3}}
+	mux := app.mount()
+
+	newRequest := func(t *testing.T, method string, userID int64, body string) *http.Request {
+		req, err := http.NewRequest(method, "/v1/posts/5/comments/3", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		token, _ := app.authenticator.GenerateToken(userID, "", "", time.Hour)
+		req.Header.Set("Authorization", "Bearer "+token)
+		return req
+	}
+
+	t.Run("it should let the author edit the comment", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, http.MethodPatch, 2, `{"content":"edited"}`), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("it should not let the post author edit the comment", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, http.MethodPatch, 1, `{"content":"edited"}`), mux)
+		checkResponseCode(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("it should not let an admin edit the comment", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, http.MethodPatch, 6, `{"content":"edited"}`), mux)
+		checkResponseCode(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("it should let an admin delete the comment", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, http.MethodDelete, 6, ""), mux)
+		checkResponseCode(t, http.StatusNoContent, rr.Code)
+	})
+
+	t.Run("it should let the post author delete the comment", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, http.MethodDelete, 1, ""), mux)
+		checkResponseCode(t, http.StatusNoContent, rr.Code)
+	})
+
+	t.Run("it should not let other users delete the comment", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, http.MethodDelete, 4, ""), mux)
+		checkResponseCode(t, http.StatusForbidden, rr.Code)
+	})
+}
+
+func TestCreateCommentAuthor(t *testing.T) {
+	app := newTestApplication(t)
+	app.store.Posts = &versionedPostStore{post: store.Post{ID: 5, UserID: 1, Status: store.StatusPublished}}
+	mux := app.mount()
+
+	testToken, _ := app.authenticator.GenerateToken(2, "", "", time.Hour)
+
+	newRequest := func(t *testing.T, body string) *http.Request {
+		req, err := http.NewRequest(http.MethodPost, "/v1/posts/5/comments", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		return req
+	}
+
+	t.Run("it should reject an author in the payload", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, `{"user_id":9,"content":"hi"}`), mux)
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("it should write the comment as the authenticated user", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, `{"content":"hi"}`), mux)
+		checkResponseCode(t, http.StatusCreated, rr.Code)
+
+		var res struct {
+			Data store.Comment `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&res); err != nil {
+			t.Fatal(err)
+		}
+		if res.Data.UserID != 2 {
+			t.Errorf("expected the comment to be written by user 2, got %d", res.Data.UserID)
+		}
+	})
+}
+
+// pagedCommentStore serves pages of two comments out of five.
+type pagedCommentStore struct {
+	store.MockCommentStore
+	query store.CommentsQuery
+}
+
+func (s *pagedCommentStore) GetByPostID(ctx context.Context, postID int64, q store.CommentsQuery) ([]store.Comment, error) {
+	s.query = q
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	return []store.Comment{{ID: 9, CreatedAt: &createdAt}, {ID: 8, CreatedAt: &createdAt}}, nil
+}
+
+func (s *pagedCommentStore) Count(ctx context.Context, postID int64) (int, error) {
+	return 5, nil
+}
+
+func TestGetCommentsByPost(t *testing.T) {
+	app := newTestApplication(t)
+	app.store.Posts = &versionedPostStore{post: store.Post{ID: 5, UserID: 1, Status: store.StatusPublished}}
+	comments := &pagedCommentStore{}
+	app.store.Comments = comments
+	mux := app.mount()
+
+	testToken, _ := app.authenticator.GenerateToken(1, "", "", time.Hour)
+
+	newRequest := func(t *testing.T, query string) *http.Request {
+		req, err := http.NewRequest(http.MethodGet, "/v1/posts/5/comments?"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		return req
+	}
+
+	t.Run("it should reject an unknown sort", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, "sort=popular"), mux)
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("it should return the total and the cursor of the next page", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, "limit=2&sort=oldest"), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		if comments.query.Sort != "oldest" || comments.query.Limit != 2 {
+			t.Errorf("expected 2 comments sorted oldest first to be requested, got %+v", comments.query)
+		}
+
+		var res struct {
+			Data       []store.Comment `json:"data"`
+			NextCursor string          `json:"next_cursor"`
+			Total      int             `json:"total"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&res); err != nil {
+			t.Fatal(err)
+		}
+		if res.Total != 5 {
+			t.Errorf("expected a total of 5 comments, got %d", res.Total)
+		}
+
+		cursor, err := store.DecodeCursor(res.NextCursor)
+		if err != nil || cursor.ID != 8 {
+			t.Errorf("expected a cursor after comment 8, got %q", res.NextCursor)
+		}
+	})
+}
+
+func TestLockedComments(t *testing.T) {
+	app := newTestApplication(t)
+	reason := "off topic"
+	app.store.Posts = &versionedPostStore{post: store.Post{ID: 5, UserID: 1, Status: store.StatusPublished, CommentsLocked: true, LockReason: &reason}}
+	mux := app.mount()
+
+	testToken, _ := app.authenticator.GenerateToken(2, "", "", time.Hour)
+
+	t.Run("it should reject new comments", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, "/v1/posts/5/comments", strings.NewReader(`{"content":"hi"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusLocked, rr.Code)
+
+		if !strings.Contains(rr.Body.String(), reason) {
+			t.Errorf("expected the lock reason in the error, got %q", rr.Body.String())
+		}
+	})
+
+	t.Run("it should only let the post author or a moderator unlock them", func(t *testing.T) {
+		app.store.Roles = &levelRoleStore{}
+
+		req, err := http.NewRequest(http.MethodDelete, "/v1/posts/5/comments/lock", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusForbidden, rr.Code)
+	})
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"github.com/lucianboboc/goBackendEngineering/internal/store"
+	"net/http"
+	"strings"
+)
+
+// postETag identifies a version of a post. Every edit bumps the version, so
+// the tag is strong and doubles as the precondition for PATCH and DELETE.
+func postETag(post *store.Post) string {
+	return fmt.Sprintf(`"post-%d-%d"`, post.ID, post.Version)
+}
+
+// postRepresentationETag extends postETag for responses that also carry
+// comments and per-viewer flags, which change without bumping the version.
+func postRepresentationETag(post *store.Post) (string, error) {
+	data, err := json.Marshal(post)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return fmt.Sprintf(`"post-%d-%d-%x"`, post.ID, post.Version, sum[:6]), nil
+}
+
+func userETag(user *store.User) (string, error) {
+	data, err := json.Marshal(user)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return fmt.Sprintf(`"user-%d-%x"`, user.ID, sum[:8]), nil
+}
+
+// ifNoneMatch reports whether the client already holds the representation
+// tagged etag. If-None-Match uses the weak comparison, so W/ prefixes are
+// ignored.
+func ifNoneMatch(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatchPost reports whether the If-Match header names the current version
+// of the post, either by its plain tag or by a full representation tag.
+// Weak tags never match.
+func ifMatchPost(r *http.Request, post *store.Post) bool {
+	etag := postETag(post)
+	withComments := strings.TrimSuffix(etag, `"`) + "-"
+
+	for _, tag := range strings.Split(r.Header.Get("If-Match"), ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == etag || strings.HasPrefix(tag, withComments) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPostPrecondition enforces If-Match on writes to a post and writes the
+// error response when the request has to stop.
+func (app *application) checkPostPrecondition(w http.ResponseWriter, r *http.Request, post *store.Post) bool {
+	if r.Header.Get("If-Match") == "" {
+		app.preconditionRequiredResponse(w, r)
+		return false
+	}
+
+	if !ifMatchPost(r, post) {
+		app.preconditionFailedResponse(w, r, fmt.Errorf("post %d is at version %d", post.ID, post.Version))
+		return false
+	}
+
+	return true
+}
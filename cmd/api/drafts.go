@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"github.com/lucianboboc/goBackendEngineering/internal/store"
+	"net/http"
+	"time"
+)
+
+// setPublishing moves the post to status, keeping the current one when status
+// is empty. Scheduled posts need a publish time in the future, the publisher
+// job takes them live once it is reached. Published posts stay published.
+func setPublishing(post *store.Post, status string, publishAt *time.Time) error {
+	if status == "" {
+		status = post.Status
+	}
+	if status == "" {
+		status = store.StatusPublished
+	}
+
+	if post.Status == store.StatusPublished && status != store.StatusPublished {
+		return errors.New("published posts cannot be turned back into drafts")
+	}
+
+	if status != store.StatusScheduled {
+		if publishAt != nil {
+			return errors.New("publish_at can only be set on scheduled posts")
+		}
+		post.Status = status
+		post.PublishAt = nil
+		return nil
+	}
+
+	if publishAt != nil {
+		if !publishAt.After(time.Now()) {
+			return errors.New("publish_at must be in the future")
+		}
+		post.PublishAt = publishAt
+	}
+	if post.PublishAt == nil {
+		return errors.New("scheduled posts need a publish_at")
+	}
+
+	post.Status = status
+	return nil
+}
+
+// getDraftsHandler godoc
+//
+//	@Summary		Fetches the drafts
+//	@Description	Fetches the drafts and scheduled posts of the user, most recently edited first. Edit them with PATCH /posts/{id}
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			limit	query		int	false	"Limit"
+//	@Param			offset	query		int	false	"Offset"
+//	@Success		200		{object}	[]store.PostWithMetadata
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/drafts [get]
+func (app *application) getDraftsHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromCtx(r)
+
+	pq := store.PaginationQuery{
+		Limit:  20,
+		Offset: 0,
+	}
+
+	pq, err := pq.Parse(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err = Validate.Struct(pq); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	posts, err := app.store.Posts.GetDrafts(r.Context(), user.ID, pq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err = app.jsonResponse(w, http.StatusOK, posts); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSchedulePost(t *testing.T) {
+	app := newTestApplication(t)
+	mux := app.mount()
+
+	testToken, _ := app.authenticator.GenerateToken(1, "", "", time.Hour)
+
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+
+	t.Run("should schedule a post", func(t *testing.T) {
+		body := `{"title":"t","content":"c","status":"scheduled","publish_at":"` + future + `"}`
+		req, err := http.NewRequest(http.MethodPost, "/v1/posts", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusCreated, rr.Code)
+	})
+
+	t.Run("should not allow scheduling a post in the past", func(t *testing.T) {
+		body := `{"title":"t","content":"c","status":"scheduled","publish_at":"` + past + `"}`
+		req, err := http.NewRequest(http.MethodPost, "/v1/posts", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should list the drafts", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/users/me/drafts", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+	})
+}
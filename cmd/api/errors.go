@@ -88,3 +88,53 @@ func (app *application) rateLimitExceededResponse(w http.ResponseWriter, r *http
 	w.Header().Set("Retry-After", retryAfter)
 	_ = writeJSONError(w, http.StatusTooManyRequests, "rate limit exceeded, retry after "+retryAfter)
 }
+
+func (app *application) preconditionFailedResponse(w http.ResponseWriter, r *http.Request, err error) {
+	app.logger.Warn(
+		"precondition failed",
+		slog.Any("method", r.Method),
+		slog.Any("path", r.URL.Path),
+		slog.Any("error", err.Error()),
+	)
+	_ = writeJSONError(w, http.StatusPreconditionFailed, "the resource was modified, fetch it again and retry")
+}
+
+func (app *application) preconditionRequiredResponse(w http.ResponseWriter, r *http.Request) {
+	app.logger.Warn(
+		"precondition required",
+		slog.Any("method", r.Method),
+		slog.Any("path", r.URL.Path),
+		slog.Any("error", "missing If-Match header"),
+	)
+	_ = writeJSONError(w, http.StatusPreconditionRequired, "the If-Match header is required")
+}
+
+func (app *application) payloadTooLargeResponse(w http.ResponseWriter, r *http.Request, err error) {
+	app.logger.Warn(
+		"payload too large",
+		slog.Any("method", r.Method),
+		slog.Any("path", r.URL.Path),
+		slog.Any("error", err.Error()),
+	)
+	_ = writeJSONError(w, http.StatusRequestEntityTooLarge, err.Error())
+}
+
+func (app *application) unsupportedMediaTypeResponse(w http.ResponseWriter, r *http.Request, err error) {
+	app.logger.Warn(
+		"unsupported media type",
+		slog.Any("method", r.Method),
+		slog.Any("path", r.URL.Path),
+		slog.Any("error", err.Error()),
+	)
+	_ = writeJSONError(w, http.StatusUnsupportedMediaType, err.Error())
+}
+
+func (app *application) lockedResponse(w http.ResponseWriter, r *http.Request, err error) {
+	app.logger.Warn(
+		"locked",
+		slog.Any("method", r.Method),
+		slog.Any("path", r.URL.Path),
+		slog.Any("error", err.Error()),
+	)
+	_ = writeJSONError(w, http.StatusLocked, err.Error())
+}
@@ -12,24 +12,27 @@ import (
 //	@Tags			feed
 //	@Accept			json
 //	@Produce		json
-//	@Param			since	query		string	false	"Since"
-//	@Param			until	query		string	false	"Until"
-//	@Param			limit	query		int		false	"Limit"
-//	@Param			offset	query		int		false	"Offset"
-//	@Param			sort	query		string	false	"Sort"
-//	@Param			tags	query		string	false	"Tags"
-//	@Param			search	query		string	false	"Search"
-//	@Success		200		{object}	[]store.PostWithMetadata
-//	@Failure		400		{object}	error
-//	@Failure		500		{object}	error
+//	@Param			since		query		string	false	"Only posts created at or after this RFC 3339 time"
+//	@Param			until		query		string	false	"Only posts created at or before this RFC 3339 time"
+//	@Param			limit		query		int		false	"Limit"
+//	@Param			offset		query		int		false	"Offset"
+//	@Param			cursor		query		string	false	"Cursor returned as next_cursor by the previous page"
+//	@Param			sort		query		string	false	"Sort by creation date: asc or desc"
+//	@Param			tags		query		string	false	"Comma separated tags"
+//	@Param			tag_match	query		string	false	"Match any or all of the tags"
+//	@Param			search		query		string	false	"Search"
+//	@Success		200			{object}	[]store.PostWithMetadata
+//	@Failure		400			{object}	error
+//	@Failure		500			{object}	error
 //	@Security		ApiKeyAuth
 //	@Router			/users/feed [get]
 func (app *application) getUserFeedHandler(w http.ResponseWriter, r *http.Request) {
 	// pagination, filter
 	fq := store.PaginatedFeedQuery{
-		Limit:  20,
-		Offset: 0,
-		Sort:   "desc",
+		Limit:    20,
+		Offset:   0,
+		Sort:     "desc",
+		TagMatch: "any",
 	}
 
 	fq, err := fq.Parse(r)
@@ -43,13 +46,32 @@ func (app *application) getUserFeedHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	feed, err := app.store.Posts.GetUserFeed(r.Context(), int64(42), fq)
+	user := getUserFromCtx(r)
+	feed, err := app.store.Posts.GetUserFeed(r.Context(), user.ID, fq)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
-	err = app.jsonResponse(w, http.StatusOK, feed)
+	for i := range feed {
+		post := &feed[i].Post
+		// a repost is a view of the original, unless it is hidden or deleted
+		if post.RepostedPostID != nil {
+			if post.RepostOf == nil {
+				continue
+			}
+			post = post.RepostOf
+		}
+		app.recordView(r, post)
+	}
+
+	var next *store.Cursor
+	if len(feed) == fq.Limit {
+		last := feed[len(feed)-1]
+		next = &store.Cursor{CreatedAt: *last.CreatedAt, ID: last.ID}
+	}
+
+	err = app.paginatedJSONResponse(w, r, http.StatusOK, feed, next)
 	if err != nil {
 		app.internalServerError(w, r, err)
 	}
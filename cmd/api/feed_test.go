@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/lucianboboc/goBackendEngineering/internal/store"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// feedStore records the arguments the handler passes to GetUserFeed.
+type feedStore struct {
+	store.MockPostStore
+	userID int64
+	fq     store.PaginatedFeedQuery
+	feed   []store.PostWithMetadata
+}
+
+func (s *feedStore) GetUserFeed(ctx context.Context, userID int64, fq store.PaginatedFeedQuery) ([]store.PostWithMetadata, error) {
+	s.userID = userID
+	s.fq = fq
+	return s.feed, nil
+}
+
+// recordingViewsStore keeps the counts flushed to it.
+type recordingViewsStore struct {
+	counts []store.ViewCount
+}
+
+func (s *recordingViewsStore) Add(ctx context.Context, counts []store.ViewCount) error {
+	s.counts = append(s.counts, counts...)
+	return nil
+}
+
+func (s *recordingViewsStore) GetDaily(ctx context.Context, postID int64, days int) ([]store.ViewCount, error) {
+	return nil, nil
+}
+
+func TestGetUserFeed(t *testing.T) {
+	app := newTestApplication(t)
+	posts := &feedStore{}
+	app.store.Posts = posts
+	mux := app.mount()
+
+	testToken, _ := app.authenticator.GenerateToken(7, "", "", time.Hour)
+
+	newFeedRequest := func(t *testing.T, query string) *http.Request {
+		req, err := http.NewRequest(http.MethodGet, "/v1/users/feed"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		return req
+	}
+
+	t.Run("it should build the feed for the authenticated user", func(t *testing.T) {
+		rr := executeRequest(newFeedRequest(t, ""), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		if posts.userID != 7 {
+			t.Errorf("expected the feed for user 7, got user %d", posts.userID)
+		}
+	})
+
+	t.Run("it should default to newest first", func(t *testing.T) {
+		rr := executeRequest(newFeedRequest(t, ""), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		if posts.fq.Sort != "desc" {
+			t.Errorf("expected sort desc, got %q", posts.fq.Sort)
+		}
+	})
+
+	t.Run("it should pass the requested sort order to the store", func(t *testing.T) {
+		rr := executeRequest(newFeedRequest(t, "?sort=asc"), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		if posts.fq.Sort != "asc" {
+			t.Errorf("expected sort asc, got %q", posts.fq.Sort)
+		}
+	})
+
+	t.Run("it should reject an unknown sort order", func(t *testing.T) {
+		rr := executeRequest(newFeedRequest(t, "?sort=sideways"), mux)
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("it should pass the search term to the store", func(t *testing.T) {
+		rr := executeRequest(newFeedRequest(t, "?search=gopher"), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		if posts.fq.Search != "gopher" {
+			t.Errorf("expected search gopher, got %q", posts.fq.Search)
+		}
+	})
+
+	t.Run("it should count a repost as a view of the original", func(t *testing.T) {
+		originalID := int64(1)
+		posts.feed = []store.PostWithMetadata{
+			{Post: store.Post{ID: 3, UserID: 8, RepostedPostID: &originalID, RepostOf: &store.Post{ID: originalID, UserID: 9}}},
+		}
+		defer func() { posts.feed = nil }()
+
+		rr := executeRequest(newFeedRequest(t, ""), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		views := &recordingViewsStore{}
+		if _, err := app.views.Flush(context.Background(), views); err != nil {
+			t.Fatal(err)
+		}
+		if len(views.counts) != 1 || views.counts[0].PostID != originalID {
+			t.Errorf("expected a view of post %d, got %+v", originalID, views.counts)
+		}
+	})
+
+	t.Run("it should return the next cursor when the page is full", func(t *testing.T) {
+		createdAt := time.Date(2024, 12, 1, 10, 0, 0, 0, time.UTC)
+		posts.feed = []store.PostWithMetadata{
+			{Post: store.Post{ID: 2, CreatedAt: &createdAt}},
+			{Post: store.Post{ID: 1, CreatedAt: &createdAt}},
+		}
+		defer func() { posts.feed = nil }()
+
+		rr := executeRequest(newFeedRequest(t, "?limit=2"), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		var body struct {
+			NextCursor string `json:"next_cursor"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+
+		cursor, err := store.DecodeCursor(body.NextCursor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cursor.ID != 1 || !cursor.CreatedAt.Equal(createdAt) {
+			t.Errorf("expected cursor at post 1, got %+v", cursor)
+		}
+
+		if link := rr.Header().Get("Link"); !strings.Contains(link, "cursor="+body.NextCursor) {
+			t.Errorf("expected Link header to carry the cursor, got %q", link)
+		}
+
+		rr = executeRequest(newFeedRequest(t, "?cursor="+body.NextCursor), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		if posts.fq.Cursor == nil || posts.fq.Cursor.ID != 1 {
+			t.Errorf("expected the store to receive the cursor, got %+v", posts.fq.Cursor)
+		}
+	})
+}
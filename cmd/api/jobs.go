@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// startJobs launches the background jobs of the API. They stop when ctx is
+// cancelled and wg is released once all of them have returned.
+func (app *application) startJobs(ctx context.Context, wg *sync.WaitGroup) {
+	app.runJob(ctx, wg, "purge deleted content", app.config.retention.interval, app.purgeDeletedContent)
+	app.runJob(ctx, wg, "publish scheduled posts", app.config.publisher.interval, app.publishScheduledPosts)
+	app.runJob(ctx, wg, "refresh trending", app.config.trending.interval, app.refreshTrending)
+	app.runJob(ctx, wg, "flush post views", app.config.views.flushInterval, app.flushViews)
+}
+
+// runJob calls fn right away and then every interval until ctx is cancelled.
+// A failed run is logged and retried on the next tick.
+func (app *application) runJob(ctx context.Context, wg *sync.WaitGroup, name string, interval time.Duration, fn func(context.Context) error) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		run := func() {
+			if err := fn(ctx); err != nil {
+				app.logger.Error("background job failed", slog.String("job", name), slog.Any("error", err.Error()))
+			}
+		}
+
+		run()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				run()
+			}
+		}
+	}()
+}
+
+func (app *application) purgeDeletedContent(ctx context.Context) error {
+	before := time.Now().Add(-app.config.retention.deletedContent)
+
+	purged, keys, err := app.store.Posts.PurgeDeleted(ctx, before)
+	if err != nil {
+		return err
+	}
+
+	// the rows are gone already, a blob that fails to delete is only logged
+	for _, key := range keys {
+		if err = app.blobStore.Delete(ctx, key); err != nil {
+			app.logger.Error("failed to delete attachment blob", slog.String("key", key), slog.Any("error", err.Error()))
+		}
+	}
+
+	if purged > 0 {
+		app.logger.Info("purged deleted posts", slog.Int64("count", purged), slog.Time("deleted before", before))
+	}
+	return nil
+}
+
+// publishScheduledPosts publishes the scheduled posts that are due, in
+// batches, until none are left. Several API instances may run it at once.
+func (app *application) publishScheduledPosts(ctx context.Context) error {
+	for {
+		published, err := app.store.Posts.PublishScheduled(ctx, app.config.publisher.batchSize)
+		if err != nil {
+			return err
+		}
+
+		if published > 0 {
+			app.logger.Info("published scheduled posts", slog.Int64("count", published))
+		}
+		if published < int64(app.config.publisher.batchSize) {
+			return nil
+		}
+	}
+}
+
+func (app *application) refreshTrending(ctx context.Context) error {
+	return app.store.Trending.Refresh(ctx, app.config.trending.window, app.config.trending.size)
+}
+
+func (app *application) flushViews(ctx context.Context) error {
+	_, err := app.views.Flush(ctx, app.store.Views)
+	return err
+}
@@ -2,7 +2,9 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"github.com/go-playground/validator/v10"
+	"github.com/lucianboboc/goBackendEngineering/internal/store"
 	"net/http"
 )
 
@@ -43,3 +45,37 @@ func (app *application) jsonResponse(w http.ResponseWriter, status int, v any) e
 
 	return writeJSON(w, status, &envelope{Data: v})
 }
+
+// page is the envelope of a paginated response. Total is only sent by the
+// lists that count their items.
+type page struct {
+	Data       any    `json:"data"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Total      *int   `json:"total,omitempty"`
+}
+
+// paginatedJSONResponse writes v inside the data envelope next to the cursor
+// of the following page. The same cursor is advertised in the Link header so
+// clients can follow rel="next" without building the URL themselves.
+func (app *application) paginatedJSONResponse(w http.ResponseWriter, r *http.Request, status int, v any, next *store.Cursor) error {
+	return app.writePage(w, r, status, &page{Data: v}, next)
+}
+
+// countedJSONResponse is paginatedJSONResponse for lists that also report how
+// many items they hold in total.
+func (app *application) countedJSONResponse(w http.ResponseWriter, r *http.Request, status int, v any, next *store.Cursor, total int) error {
+	return app.writePage(w, r, status, &page{Data: v, Total: &total}, next)
+}
+
+func (app *application) writePage(w http.ResponseWriter, r *http.Request, status int, env *page, next *store.Cursor) error {
+	if next != nil {
+		env.NextCursor = next.Encode()
+
+		qs := r.URL.Query()
+		qs.Del("offset")
+		qs.Set("cursor", env.NextCursor)
+		w.Header().Set("Link", fmt.Sprintf(`<%s?%s>; rel="next"`, r.URL.Path, qs.Encode()))
+	}
+
+	return writeJSON(w, status, env)
+}
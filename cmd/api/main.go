@@ -4,12 +4,14 @@ import (
 	"expvar"
 	"github.com/joho/godotenv"
 	"github.com/lucianboboc/goBackendEngineering/internal/auth"
+	"github.com/lucianboboc/goBackendEngineering/internal/blob"
 	"github.com/lucianboboc/goBackendEngineering/internal/db"
 	"github.com/lucianboboc/goBackendEngineering/internal/env"
 	"github.com/lucianboboc/goBackendEngineering/internal/mailer"
 	"github.com/lucianboboc/goBackendEngineering/internal/ratelimiter"
 	"github.com/lucianboboc/goBackendEngineering/internal/store"
 	"github.com/lucianboboc/goBackendEngineering/internal/store/cache"
+	"github.com/lucianboboc/goBackendEngineering/internal/views"
 	"github.com/redis/go-redis/v9"
 	"log/slog"
 	"os"
@@ -93,6 +95,74 @@ func main() {
 			TimeFrame:            time.Second * 5,
 			Enabled:              env.GetBool("RATE_LIMITER_ENABLED", true),
 		},
+		retention: retentionConfig{
+			deletedContent: env.GetDuration("DELETED_CONTENT_RETENTION", time.Hour*24*30),
+			interval:       env.GetDuration("DELETED_CONTENT_PURGE_INTERVAL", time.Hour),
+		},
+		publisher: publisherConfig{
+			interval:  env.GetDuration("SCHEDULED_POSTS_PUBLISH_INTERVAL", time.Second*30),
+			batchSize: env.GetInt("SCHEDULED_POSTS_BATCH_SIZE", 100),
+		},
+		trending: trendingConfig{
+			window:   env.GetDuration("TRENDING_WINDOW", time.Hour*48),
+			interval: env.GetDuration("TRENDING_REFRESH_INTERVAL", time.Minute*5),
+			size:     env.GetInt("TRENDING_SIZE", 500),
+		},
+		views: viewsConfig{
+			window:        env.GetDuration("VIEWS_DEDUP_WINDOW", time.Minute*30),
+			flushInterval: env.GetDuration("VIEWS_FLUSH_INTERVAL", time.Second*10),
+		},
+		pins: pinsConfig{
+			limit: env.GetInt("MAX_PINNED_POSTS", 3),
+		},
+		uploads: uploadsConfig{
+			dir:         env.GetString("UPLOADS_DIR", "./uploads"),
+			maxFileSize: int64(env.GetInt("UPLOADS_MAX_FILE_SIZE", 10<<20)),
+			maxFiles:    env.GetInt("UPLOADS_MAX_FILES", 4),
+		},
+	}
+
+	// a negative retention would purge soft deleted content right away
+	if cfg.retention.deletedContent <= 0 {
+		logger.Error("DELETED_CONTENT_RETENTION must be greater than 0")
+		os.Exit(1)
+	}
+
+	if cfg.retention.interval <= 0 {
+		logger.Error("DELETED_CONTENT_PURGE_INTERVAL must be greater than 0")
+		os.Exit(1)
+	}
+
+	// the publisher runs batches until one comes back short
+	if cfg.publisher.batchSize < 1 {
+		logger.Error("SCHEDULED_POSTS_BATCH_SIZE must be greater than 0")
+		os.Exit(1)
+	}
+
+	if cfg.publisher.interval <= 0 {
+		logger.Error("SCHEDULED_POSTS_PUBLISH_INTERVAL must be greater than 0")
+		os.Exit(1)
+	}
+
+	// the trending scores divide by the window
+	if cfg.trending.window <= 0 {
+		logger.Error("TRENDING_WINDOW must be greater than 0")
+		os.Exit(1)
+	}
+
+	if cfg.trending.interval <= 0 {
+		logger.Error("TRENDING_REFRESH_INTERVAL must be greater than 0")
+		os.Exit(1)
+	}
+
+	if cfg.views.window <= 0 {
+		logger.Error("VIEWS_DEDUP_WINDOW must be greater than 0")
+		os.Exit(1)
+	}
+
+	if cfg.views.flushInterval <= 0 {
+		logger.Error("VIEWS_FLUSH_INTERVAL must be greater than 0")
+		os.Exit(1)
 	}
 
 	// Database
@@ -123,6 +193,13 @@ func main() {
 		cfg.ratelimiter.TimeFrame,
 	)
 
+	// Blob storage
+	blobStore, err := blob.NewLocalStore(cfg.uploads.dir)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
 	storage := store.NewPostgresStorage(db)
 	cacheStore := cache.NewRedisStorage(rdb)
 
@@ -138,6 +215,8 @@ func main() {
 		mailer:        sendGridMailer,
 		authenticator: nwtAuthenticator,
 		rateLimiter:   rateLimiter,
+		blobStore:     blobStore,
+		views:         views.NewCounter(cfg.views.window),
 	}
 
 	// Metrics collected
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestGetMentions(t *testing.T) {
+	app := newTestApplication(t)
+	mux := app.mount()
+
+	testToken, _ := app.authenticator.GenerateToken(1, "", "", time.Hour)
+
+	t.Run("should list the posts mentioning the user", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/users/me/mentions", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("should not allow an invalid limit", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/users/me/mentions?limit=0", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"github.com/lucianboboc/goBackendEngineering/internal/store"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestPinPost(t *testing.T) {
+	app := newTestApplication(t)
+	mux := app.mount()
+
+	testToken, _ := app.authenticator.GenerateToken(1, "", "", time.Hour)
+
+	t.Run("should let the author pin the post", func(t *testing.T) {
+		app.store.Posts = &versionedPostStore{post: store.Post{ID: 5, UserID: 1, Status: store.StatusPublished}}
+
+		req, err := http.NewRequest(http.MethodPut, "/v1/posts/5/pin", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("should not allow pinning a draft", func(t *testing.T) {
+		app.store.Posts = &versionedPostStore{post: store.Post{ID: 5, UserID: 1, Status: store.StatusDraft}}
+
+		req, err := http.NewRequest(http.MethodPut, "/v1/posts/5/pin", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should list the posts of the user", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/users/1/posts", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+	})
+}
@@ -3,45 +3,79 @@ package main
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/go-chi/chi/v5"
+	"github.com/lucianboboc/goBackendEngineering/internal/hashtag"
 	"github.com/lucianboboc/goBackendEngineering/internal/store"
+	"mime/multipart"
 	"net/http"
 	"strconv"
+	"time"
 )
 
 type postKey string
 
-const postCtx postKey = "post"
+const (
+	postCtx        postKey = "post"
+	postVisibleCtx postKey = "post_visible"
+)
 
 type CreatePostPayload struct {
-	Content string   `json:"content" validate:"required,max=100"`
-	Title   string   `json:"title" validate:"required,max=1000"`
-	Tags    []string `json:"tags" validate:"required"`
+	Content      string     `json:"content" validate:"required,max=100"`
+	Title        string     `json:"title" validate:"required,max=1000"`
+	Tags         []string   `json:"tags" validate:"max=20"`
+	QuotedPostID *int64     `json:"quoted_post_id" validate:"omitempty,min=1"`
+	Visibility   string     `json:"visibility" validate:"omitempty,oneof=public followers mentioned private"`
+	Status       string     `json:"status" validate:"omitempty,oneof=draft scheduled published"`
+	PublishAt    *time.Time `json:"publish_at"`
 }
 
 type UpdatePostPayload struct {
-	Content *string `json:"content" validate:"omitempty,max=100"`
-	Title   *string `json:"title" validate:"omitempty,max=1000"`
+	Content    *string    `json:"content" validate:"omitempty,max=100"`
+	Title      *string    `json:"title" validate:"omitempty,max=1000"`
+	Visibility *string    `json:"visibility" validate:"omitempty,oneof=public followers mentioned private"`
+	Status     *string    `json:"status" validate:"omitempty,oneof=draft scheduled published"`
+	PublishAt  *time.Time `json:"publish_at"`
 }
 
 // createPostsHandler godoc
 //
 //	@Summary		Create a post
-//	@Description	Create a new post
+//	@Description	Create a new post, or a draft or scheduled post depending on status. The #hashtags of the title and content are added to the tags. Send multipart/form-data with the payload fields and "attachments" files to upload media
 //	@Tags			posts
-//	@Accept			json
+//	@Accept			json,mpfd
 //	@Produce		json
-//	@Success		200		{object}	store.Post
-//	@Param			payload	body		CreatePostPayload	true	"Post payload"
-//	@Failure		400		{object}	error
-//	@Failure		401		{object}	error
-//	@Failure		404		{object}	error
-//	@Failure		500		{object}	error
+//	@Success		200			{object}	store.Post
+//	@Param			payload		body		CreatePostPayload	true	"Post payload"
+//	@Param			attachments	formData	file				false	"Files to attach, multipart requests only"
+//	@Failure		400			{object}	error
+//	@Failure		401			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		413			{object}	error
+//	@Failure		415			{object}	error
+//	@Failure		500			{object}	error
 //	@Security		ApiKeyAuth
 //	@Router			/posts/ [post]
 func (app *application) createPostsHandler(w http.ResponseWriter, r *http.Request) {
 	var payload CreatePostPayload
-	if err := readJSON(w, r, &payload); err != nil {
+	var files []*multipart.FileHeader
+	if isMultipartForm(r) {
+		var err error
+		files, err = app.readPostForm(w, r, &payload)
+		if r.MultipartForm != nil {
+			defer r.MultipartForm.RemoveAll()
+		}
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			switch {
+			case errors.As(err, &maxBytesErr), errors.Is(err, errAttachmentTooLarge):
+				app.payloadTooLargeResponse(w, r, err)
+			default:
+				app.badRequestResponse(w, r, err)
+			}
+			return
+		}
+	} else if err := readJSON(w, r, &payload); err != nil {
 		app.badRequestResponse(w, r, err)
 		return
 	}
@@ -51,16 +85,67 @@ func (app *application) createPostsHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	for _, t := range payload.Tags {
+		if _, ok := hashtag.Normalize(t); !ok {
+			app.badRequestResponse(w, r, fmt.Errorf("invalid tag %q", t))
+			return
+		}
+	}
+
 	user := getUserFromCtx(r)
 	post := &store.Post{
-		Content: payload.Content,
-		Title:   payload.Title,
-		Tags:    payload.Tags,
-		UserID:  user.ID,
+		Content:    payload.Content,
+		Title:      payload.Title,
+		Tags:       payload.Tags,
+		UserID:     user.ID,
+		Visibility: payload.Visibility,
+	}
+
+	if err := setPublishing(post, payload.Status, payload.PublishAt); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if payload.QuotedPostID != nil {
+		quoted, err := app.getVisiblePost(r.Context(), *payload.QuotedPostID, user.ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFoundResponse(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+		if quoted.Status != store.StatusPublished {
+			app.badRequestResponse(w, r, errors.New("only published posts can be quoted"))
+			return
+		}
+		quotedID := originalPostID(quoted)
+		post.QuotedPostID = &quotedID
 	}
-	err := app.store.Posts.Create(r.Context(), post)
+
+	attachments, err := app.storeAttachments(r.Context(), files)
 	if err != nil {
-		app.internalServerError(w, r, err)
+		switch {
+		case errors.Is(err, errUnsupportedAttachmentType):
+			app.unsupportedMediaTypeResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	post.Attachments = attachments
+
+	err = app.store.Posts.Create(r.Context(), post)
+	if err != nil {
+		app.deleteAttachmentBlobs(r.Context(), attachments)
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
 		return
 	}
 
@@ -72,35 +157,133 @@ func (app *application) createPostsHandler(w http.ResponseWriter, r *http.Reques
 
 // getPostsHandler godoc
 //
-//	@Summary		Fetches all posts
-//	@Description	Fetches all posts
+//	@Summary		Fetches the explore timeline
+//	@Description	Fetches a page of all posts, newest or most commented first
 //	@Tags			posts
 //	@Accept			json
 //	@Produce		json
-//	@Success		200	{object}	store.Post
-//	@Failure		400	{object}	error
-//	@Failure		404	{object}	error
-//	@Failure		500	{object}	error
+//	@Param			author_id	query		int		false	"Only posts written by this user"
+//	@Param			tags		query		string	false	"Comma separated tags"
+//	@Param			tag_match	query		string	false	"Match any or all of the tags"
+//	@Param			search		query		string	false	"Search in title and content"
+//	@Param			since		query		string	false	"Only posts created at or after this RFC 3339 time"
+//	@Param			until		query		string	false	"Only posts created at or before this RFC 3339 time"
+//	@Param			sort		query		string	false	"newest or most_commented"
+//	@Param			limit		query		int		false	"Limit"
+//	@Param			offset		query		int		false	"Offset"
+//	@Param			cursor		query		string	false	"Cursor returned as next_cursor by the previous page"
+//	@Success		200			{object}	[]store.PostWithMetadata
+//	@Failure		400			{object}	error
+//	@Failure		500			{object}	error
 //	@Security		ApiKeyAuth
 //	@Router			/posts [get]
 func (app *application) getPostsHandler(w http.ResponseWriter, r *http.Request) {
-	posts, err := app.store.Posts.GetAllPosts(r.Context())
+	pq := store.PaginatedPostsQuery{
+		Limit:    20,
+		Offset:   0,
+		Sort:     "newest",
+		TagMatch: "any",
+	}
+
+	pq, err := pq.Parse(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err = Validate.Struct(pq); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if pq.Cursor != nil && pq.Sort != "newest" {
+		app.badRequestResponse(w, r, errors.New("cursor can only be used when sorting by newest"))
+		return
+	}
+
+	user := getUserFromCtx(r)
+	posts := make([]store.PostWithMetadata, 0, pq.Limit)
+	err = app.store.Posts.GetAllPosts(r.Context(), user.ID, pq, func(post *store.PostWithMetadata) error {
+		posts = append(posts, *post)
+		return nil
+	})
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
-	_ = app.jsonResponse(w, http.StatusOK, posts)
+
+	var next *store.Cursor
+	if pq.Sort == "newest" && len(posts) == pq.Limit {
+		last := posts[len(posts)-1]
+		next = &store.Cursor{CreatedAt: *last.CreatedAt, ID: last.ID}
+	}
+
+	if err = app.paginatedJSONResponse(w, r, http.StatusOK, posts, next); err != nil {
+		app.internalServerError(w, r, err)
+	}
 }
 
+// getPostHandler godoc
+//
+//	@Summary		Fetches a post
+//	@Description	Fetches a post with its newest comments, the others are listed by GET /posts/{id}/comments. Supports If-None-Match with the returned ETag
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			id				path		int		true	"Post ID"
+//	@Param			If-None-Match	header		string	false	"ETag of a cached copy"
+//	@Success		200				{object}	store.Post
+//	@Success		304				{string}	string	"Not modified"
+//	@Failure		404				{object}	error
+//	@Failure		500				{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id} [get]
 func (app *application) getPostHandler(w http.ResponseWriter, r *http.Request) {
 	post := getPostFromCtx(r)
-	comments, err := app.store.Comments.GetByPostID(r.Context(), post.ID)
+	if isPostVisibleFromCtx(r) {
+		app.recordView(r, post)
+	}
+	comments, err := app.store.Comments.GetByPostID(r.Context(), post.ID, store.CommentsQuery{Limit: 20, Sort: "newest"})
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 	post.Comments = comments
 
+	user := getUserFromCtx(r)
+	post.IsBookmarked, err = app.store.Bookmarks.Exists(r.Context(), user.ID, post.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	post.Attachments, err = app.store.Attachments.GetByPostID(r.Context(), post.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if post.RepostOf, err = app.getEmbeddedPost(r, post.RepostedPostID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if post.QuotedPost, err = app.getEmbeddedPost(r, post.QuotedPostID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	etag, err := postRepresentationETag(post)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag)
+	if ifNoneMatch(r, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
 	if err = app.jsonResponse(w, http.StatusOK, post); err != nil {
 		app.internalServerError(w, r, err)
 	}
@@ -113,17 +296,28 @@ func (app *application) getPostHandler(w http.ResponseWriter, r *http.Request) {
 //	@Tags			posts
 //	@Accept			json
 //	@Produce		json
-//	@Param			id		path		int					true	"Post ID"
-//	@Param			payload	body		UpdatePostPayload	true	"Post payload"
-//	@Success		200		{object}	store.Post
-//	@Failure		400		{object}	error
-//	@Failure		401		{object}	error
-//	@Failure		404		{object}	error
-//	@Failure		500		{object}	error
+//	@Param			id			path		int					true	"Post ID"
+//	@Param			If-Match	header		string				true	"ETag of the version being edited"
+//	@Param			payload		body		UpdatePostPayload	true	"Post payload"
+//	@Success		200			{object}	store.Post
+//	@Failure		400			{object}	error
+//	@Failure		401			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		412			{object}	error
+//	@Failure		428			{object}	error
+//	@Failure		500			{object}	error
 //	@Security		ApiKeyAuth
 //	@Router			/posts/{id} [patch]
 func (app *application) updatePostHandler(w http.ResponseWriter, r *http.Request) {
 	post := getPostFromCtx(r)
+	if !app.checkPostPrecondition(w, r, post) {
+		return
+	}
+
+	if post.RepostedPostID != nil {
+		app.badRequestResponse(w, r, errors.New("reposts cannot be edited"))
+		return
+	}
 
 	var payload UpdatePostPayload
 	if err := readJSON(w, r, &payload); err != nil {
@@ -142,22 +336,64 @@ func (app *application) updatePostHandler(w http.ResponseWriter, r *http.Request
 	if payload.Title != nil {
 		post.Title = *payload.Title
 	}
+	if payload.Visibility != nil {
+		post.Visibility = *payload.Visibility
+	}
+
+	var status string
+	if payload.Status != nil {
+		status = *payload.Status
+	}
+	if err := setPublishing(post, status, payload.PublishAt); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
 
-	err := app.store.Posts.UpdatePost(r.Context(), post)
+	user := getUserFromCtx(r)
+	err := app.store.Posts.UpdatePost(r.Context(), post, user.ID)
 	if err != nil {
-		app.internalServerError(w, r, err)
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			// the post exists, so someone else saved a newer version first
+			app.preconditionFailedResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
 		return
 	}
 
+	w.Header().Set("ETag", postETag(post))
 	err = app.jsonResponse(w, http.StatusOK, post)
 	if err != nil {
 		app.internalServerError(w, r, err)
 	}
 }
 
+// deletePostHandler godoc
+//
+//	@Summary		Deletes a post
+//	@Description	Deletes a post by id
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int		true	"Post ID"
+//	@Param			If-Match	header		string	true	"ETag of the version being deleted"
+//	@Success		200			{object}	store.Post
+//	@Failure		403			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		412			{object}	error
+//	@Failure		428			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id} [delete]
 func (app *application) deletePostHandler(w http.ResponseWriter, r *http.Request) {
 	post := getPostFromCtx(r)
-	err := app.store.Posts.DeletePost(r.Context(), post.ID)
+	if !app.checkPostPrecondition(w, r, post) {
+		return
+	}
+
+	user := getUserFromCtx(r)
+	err := app.store.Posts.DeletePost(r.Context(), post.ID, user.ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, store.ErrNotFound):
@@ -174,7 +410,66 @@ func (app *application) deletePostHandler(w http.ResponseWriter, r *http.Request
 	}
 }
 
+// restorePostHandler godoc
+//
+//	@Summary		Restores a deleted post
+//	@Description	Restores a deleted post and the comments deleted with it, before the retention period purges them
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"Post ID"
+//	@Success		200	{object}	store.Post
+//	@Failure		403	{object}	error
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/restore [put]
+func (app *application) restorePostHandler(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.ParseInt(chi.URLParam(r, "post_id"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	err = app.store.Posts.RestorePost(r.Context(), postID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	post, err := app.store.Posts.GetPostByID(r.Context(), postID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err = app.jsonResponse(w, http.StatusOK, post); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
 func (app *application) postsContextMiddleware(next http.Handler) http.Handler {
+	return app.postContext(next, func(ctx context.Context, postID int64, user *store.User) (*store.Post, bool, error) {
+		post, err := app.getVisiblePost(ctx, postID, user.ID)
+		return post, true, err
+	})
+}
+
+// moderatedPostsContextMiddleware is postsContextMiddleware for the routes
+// behind checkPostOwnership, where moderators also reach the posts hidden from
+// them.
+func (app *application) moderatedPostsContextMiddleware(next http.Handler) http.Handler {
+	return app.postContext(next, app.getModeratedPost)
+}
+
+// postContext puts the post returned by getPost in the request context, along
+// with whether the user may see it or only reached it as a moderator.
+func (app *application) postContext(next http.Handler, getPost func(context.Context, int64, *store.User) (*store.Post, bool, error)) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		postIDStr := chi.URLParam(r, "post_id")
 		postID, err := strconv.ParseInt(postIDStr, 10, 64)
@@ -183,7 +478,8 @@ func (app *application) postsContextMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		post, err := app.store.Posts.GetPostByID(r.Context(), postID)
+		user := getUserFromCtx(r)
+		post, visible, err := getPost(r.Context(), postID, user)
 		if err != nil {
 			switch {
 			case errors.Is(err, store.ErrNotFound):
@@ -195,10 +491,64 @@ func (app *application) postsContextMiddleware(next http.Handler) http.Handler {
 		}
 
 		ctx := context.WithValue(r.Context(), postCtx, post)
+		ctx = context.WithValue(ctx, postVisibleCtx, visible)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// getVisiblePost loads the post and answers store.ErrNotFound when the viewer
+// may not see it, so hidden posts look exactly like missing ones.
+func (app *application) getVisiblePost(ctx context.Context, postID, viewerID int64) (*store.Post, error) {
+	post, err := app.store.Posts.GetPostByID(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	visible, err := app.store.Posts.CanView(ctx, post, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	if !visible {
+		return nil, store.ErrNotFound
+	}
+
+	return post, nil
+}
+
+// getModeratedPost is getVisiblePost for moderators, who also get the posts
+// hidden from them so they can edit, delete or lock them. It reports whether
+// the user may see the post.
+func (app *application) getModeratedPost(ctx context.Context, postID int64, user *store.User) (*store.Post, bool, error) {
+	post, err := app.store.Posts.GetPostByID(ctx, postID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	visible, err := app.store.Posts.CanView(ctx, post, user.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	if visible {
+		return post, true, nil
+	}
+
+	moderator, err := app.checkRolePrecedence(ctx, user, "moderator")
+	if err != nil {
+		return nil, false, err
+	}
+	if !moderator {
+		return nil, false, store.ErrNotFound
+	}
+
+	return post, false, nil
+}
+
 func getPostFromCtx(r *http.Request) *store.Post {
 	return r.Context().Value(postCtx).(*store.Post)
 }
+
+// isPostVisibleFromCtx tells whether the user may see the post in the context,
+// rather than only reaching it as a moderator.
+func isPostVisibleFromCtx(r *http.Request) bool {
+	return r.Context().Value(postVisibleCtx).(bool)
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/lucianboboc/goBackendEngineering/internal/blob"
+	"github.com/lucianboboc/goBackendEngineering/internal/store"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// versionedPostStore serves a single post and behaves like UpdatePost when a
+// concurrent writer already bumped the version.
+type versionedPostStore struct {
+	store.MockPostStore
+	post      store.Post
+	staleSave bool
+}
+
+func (s *versionedPostStore) GetPostByID(ctx context.Context, id int64) (*store.Post, error) {
+	if id != s.post.ID {
+		return nil, store.ErrNotFound
+	}
+	post := s.post
+	return &post, nil
+}
+
+func (s *versionedPostStore) UpdatePost(ctx context.Context, post *store.Post, editorID int64) error {
+	if s.staleSave {
+		return store.ErrNotFound
+	}
+	post.Version++
+	return nil
+}
+
+func TestPostConditionalRequests(t *testing.T) {
+	app := newTestApplication(t)
+	posts := &versionedPostStore{post: store.Post{ID: 5, UserID: 1, Version: 3}}
+	app.store.Posts = posts
+	mux := app.mount()
+
+	testToken, _ := app.authenticator.GenerateToken(1, "", "", time.Hour)
+
+	newRequest := func(t *testing.T, method, body string, headers map[string]string) *http.Request {
+		req, err := http.NewRequest(method, "/v1/posts/5", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		return req
+	}
+
+	var etag string
+
+	t.Run("it should return an etag for the post", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, http.MethodGet, "", nil), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		etag = rr.Header().Get("ETag")
+		if !strings.HasPrefix(etag, `"post-5-3`) {
+			t.Errorf("expected an etag for post 5 at version 3, got %q", etag)
+		}
+	})
+
+	t.Run("it should answer not modified for a cached copy", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, http.MethodGet, "", map[string]string{"If-None-Match": etag}), mux)
+		checkResponseCode(t, http.StatusNotModified, rr.Code)
+
+		if rr.Body.Len() != 0 {
+			t.Errorf("expected an empty body, got %q", rr.Body.String())
+		}
+	})
+
+	t.Run("it should require if-match to update", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, http.MethodPatch, `{"title":"new"}`, nil), mux)
+		checkResponseCode(t, http.StatusPreconditionRequired, rr.Code)
+	})
+
+	t.Run("it should reject an update of an older version", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, http.MethodPatch, `{"title":"new"}`, map[string]string{"If-Match": `"post-5-2"`}), mux)
+		checkResponseCode(t, http.StatusPreconditionFailed, rr.Code)
+	})
+
+	t.Run("it should reject an update that lost the race", func(t *testing.T) {
+		posts.staleSave = true
+		defer func() { posts.staleSave = false }()
+
+		rr := executeRequest(newRequest(t, http.MethodPatch, `{"title":"new"}`, map[string]string{"If-Match": etag}), mux)
+		checkResponseCode(t, http.StatusPreconditionFailed, rr.Code)
+	})
+
+	t.Run("it should update the current version and return the new etag", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, http.MethodPatch, `{"title":"new"}`, map[string]string{"If-Match": etag}), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		if got := rr.Header().Get("ETag"); got != `"post-5-4"` {
+			t.Errorf("expected etag for version 4, got %q", got)
+		}
+	})
+
+	t.Run("it should require if-match to delete", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, http.MethodDelete, "", nil), mux)
+		checkResponseCode(t, http.StatusPreconditionRequired, rr.Code)
+	})
+}
+
+func TestCreatePostWithAttachments(t *testing.T) {
+	app := newTestApplication(t)
+	blobStore, err := blob.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.blobStore = blobStore
+	app.config.uploads = uploadsConfig{maxFileSize: 1024, maxFiles: 2}
+	mux := app.mount()
+
+	testToken, _ := app.authenticator.GenerateToken(1, "", "", time.Hour)
+
+	newRequest := func(t *testing.T, filename string, content []byte) *http.Request {
+		body := &bytes.Buffer{}
+		mw := multipart.NewWriter(body)
+		_ = mw.WriteField("title", "title")
+		_ = mw.WriteField("content", "content")
+		_ = mw.WriteField("tags", "go")
+		fw, err := mw.CreateFormFile("attachments", filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = fw.Write(content)
+		_ = mw.Close()
+
+		req, err := http.NewRequest(http.MethodPost, "/v1/posts/", body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		return req
+	}
+
+	png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 64)...)
+
+	t.Run("it should store the file and return its metadata", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, "gopher.png", png), mux)
+		checkResponseCode(t, http.StatusCreated, rr.Code)
+
+		var res struct {
+			Data store.Post `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&res); err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Data.Attachments) != 1 {
+			t.Fatalf("expected 1 attachment, got %d", len(res.Data.Attachments))
+		}
+
+		a := res.Data.Attachments[0]
+		sum := sha256.Sum256(png)
+		if a.ContentType != "image/png" || a.Size != int64(len(png)) || a.Checksum != hex.EncodeToString(sum[:]) {
+			t.Errorf("unexpected attachment metadata %+v", a)
+		}
+	})
+
+	t.Run("it should reject files of an unsupported type", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, "gopher.png", []byte("#!/bin/sh\necho hi\n")), mux)
+		checkResponseCode(t, http.StatusUnsupportedMediaType, rr.Code)
+	})
+
+	t.Run("it should reject files over the size limit", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, "gopher.png", append(png, make([]byte, 1024)...)), mux)
+		checkResponseCode(t, http.StatusRequestEntityTooLarge, rr.Code)
+	})
+}
+
+// hiddenPostStore serves a private post written by another user.
+type hiddenPostStore struct {
+	store.MockPostStore
+}
+
+func (s *hiddenPostStore) GetPostByID(ctx context.Context, id int64) (*store.Post, error) {
+	return &store.Post{ID: id, UserID: 2, Visibility: store.VisibilityPrivate}, nil
+}
+
+func (s *hiddenPostStore) CanView(ctx context.Context, post *store.Post, viewerID int64) (bool, error) {
+	return post.UserID == viewerID, nil
+}
+
+func TestPostVisibility(t *testing.T) {
+	app := newTestApplication(t)
+	app.store.Posts = &hiddenPostStore{}
+	app.store.Comments = &singleCommentStore{}
+	app.store.Roles = &levelRoleStore{}
+	// user 4 is a moderator and user 6 an admin
+	app.store.Users = &roleUserStore{levels: map[int64]int64{1: 1, 4: 2, 6: 3}}
+	mux := app.mount()
+
+	testToken, _ := app.authenticator.GenerateToken(1, "", "", time.Hour)
+
+	for _, path := range []string{"/v1/posts/7", "/v1/posts/7/comments", "/v1/posts/7/reactions"} {
+		t.Run("it should hide "+path+" from other users", func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Authorization", "Bearer "+testToken)
+
+			rr := executeRequest(req, mux)
+			checkResponseCode(t, http.StatusNotFound, rr.Code)
+		})
+	}
+
+	t.Run("it should hide the post from moderators", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/posts/7", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		moderatorToken, _ := app.authenticator.GenerateToken(4, "", "", time.Hour)
+		req.Header.Set("Authorization", "Bearer "+moderatorToken)
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("it should let moderators lock the comments of hidden posts", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPut, "/v1/posts/7/comments/lock", strings.NewReader(`{"reason":"spam"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		moderatorToken, _ := app.authenticator.GenerateToken(4, "", "", time.Hour)
+		req.Header.Set("Authorization", "Bearer "+moderatorToken)
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("it should let moderators delete the comments of hidden posts", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodDelete, "/v1/posts/5/comments/3", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		moderatorToken, _ := app.authenticator.GenerateToken(4, "", "", time.Hour)
+		req.Header.Set("Authorization", "Bearer "+moderatorToken)
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusNoContent, rr.Code)
+	})
+
+	t.Run("it should let admins delete hidden posts", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodDelete, "/v1/posts/7", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		adminToken, _ := app.authenticator.GenerateToken(6, "", "", time.Hour)
+		req.Header.Set("Authorization", "Bearer "+adminToken)
+		req.Header.Set("If-Match", `"post-7-0"`)
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+	})
+}
+
+// postsByIDStore serves the posts it holds by id.
+type postsByIDStore struct {
+	store.MockPostStore
+	posts map[int64]store.Post
+}
+
+func (s *postsByIDStore) GetPostByID(ctx context.Context, id int64) (*store.Post, error) {
+	post, ok := s.posts[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return &post, nil
+}
+
+func TestGetRepost(t *testing.T) {
+	app := newTestApplication(t)
+	originalID := int64(1)
+	posts := &postsByIDStore{posts: map[int64]store.Post{
+		1: {ID: 1, UserID: 1, Status: store.StatusPublished, Visibility: store.VisibilityPublic},
+		5: {ID: 5, UserID: 1, Status: store.StatusPublished, Visibility: store.VisibilityPublic, RepostedPostID: &originalID},
+	}}
+	app.store.Posts = posts
+	mux := app.mount()
+
+	testToken, _ := app.authenticator.GenerateToken(1, "", "", time.Hour)
+
+	getRepost := func(t *testing.T) store.Post {
+		req, err := http.NewRequest(http.MethodGet, "/v1/posts/5", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		var res struct {
+			Data store.Post `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&res); err != nil {
+			t.Fatal(err)
+		}
+		return res.Data
+	}
+
+	t.Run("it should embed the original post", func(t *testing.T) {
+		if post := getRepost(t); post.RepostOf == nil || post.RepostOf.ID != originalID {
+			t.Errorf("expected post %d to be embedded, got %+v", originalID, post.RepostOf)
+		}
+	})
+
+	t.Run("it should leave out an original moved back to draft", func(t *testing.T) {
+		posts.posts[1] = store.Post{ID: 1, UserID: 1, Status: store.StatusDraft, Visibility: store.VisibilityPublic}
+
+		if post := getRepost(t); post.RepostOf != nil {
+			t.Errorf("expected the draft to be left out, got %+v", post.RepostOf)
+		}
+	})
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/lucianboboc/goBackendEngineering/internal/store"
+	"net/http"
+	"slices"
+)
+
+// reactPostHandler godoc
+//
+//	@Summary		Reacts to a post
+//	@Description	Leaves a reaction on a post, replacing the previous reaction of the user
+//	@Tags			reactions
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int		true	"Post ID"
+//	@Param			type	path		string	true	"like, love, laugh, wow, sad or angry"
+//	@Success		204		{string}	string	"Reaction saved"
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/reactions/{type} [put]
+func (app *application) reactPostHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromCtx(r)
+	user := getUserFromCtx(r)
+
+	reactionType, err := getReactionTypeParam(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	reaction := &store.Reaction{
+		PostID: post.ID,
+		UserID: user.ID,
+		Type:   reactionType,
+	}
+	if err = app.store.Reactions.Set(r.Context(), reaction); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err = app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// unreactPostHandler godoc
+//
+//	@Summary		Removes a reaction from a post
+//	@Description	Removes the reaction of the user from a post
+//	@Tags			reactions
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int		true	"Post ID"
+//	@Param			type	path		string	true	"like, love, laugh, wow, sad or angry"
+//	@Success		204		{string}	string	"Reaction removed"
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/reactions/{type} [delete]
+func (app *application) unreactPostHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromCtx(r)
+	user := getUserFromCtx(r)
+
+	reactionType, err := getReactionTypeParam(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	err = app.store.Reactions.Delete(r.Context(), post.ID, user.ID, reactionType)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err = app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getPostReactionsHandler godoc
+//
+//	@Summary		Fetches the reactions of a post
+//	@Description	Fetches how many reactions of each type a post has and the reaction of the user
+//	@Tags			reactions
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"Post ID"
+//	@Success		200	{object}	store.ReactionSummary
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/reactions [get]
+func (app *application) getPostReactionsHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromCtx(r)
+	user := getUserFromCtx(r)
+
+	summary, err := app.store.Reactions.GetSummary(r.Context(), post.ID, user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err = app.jsonResponse(w, http.StatusOK, summary); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func getReactionTypeParam(r *http.Request) (string, error) {
+	reactionType := chi.URLParam(r, "type")
+	if !slices.Contains(store.ReactionTypes, reactionType) {
+		return "", fmt.Errorf("unknown reaction %q", reactionType)
+	}
+	return reactionType, nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"github.com/lucianboboc/goBackendEngineering/internal/store"
+	"net/http"
+)
+
+// repostHandler godoc
+//
+//	@Summary		Reposts a post
+//	@Description	Shares a public post with the followers of the user. Reposting a repost shares the original post
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"Post ID"
+//	@Success		201	{object}	store.Post
+//	@Failure		400	{object}	error
+//	@Failure		404	{object}	error
+//	@Failure		409	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/repost [put]
+func (app *application) repostHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromCtx(r)
+	user := getUserFromCtx(r)
+
+	original := post
+	if post.RepostedPostID != nil {
+		var err error
+		original, err = app.getVisiblePost(r.Context(), *post.RepostedPostID, user.ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFoundResponse(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+	}
+
+	// reposts are shown to everyone following the user
+	if original.Status != store.StatusPublished || original.Visibility != store.VisibilityPublic {
+		app.badRequestResponse(w, r, errors.New("only public posts can be reposted"))
+		return
+	}
+
+	repost, err := app.store.Posts.Repost(r.Context(), user.ID, original.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err = app.jsonResponse(w, http.StatusCreated, repost); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// unrepostHandler godoc
+//
+//	@Summary		Removes a repost
+//	@Description	Removes the repost of a post made by the user
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int		true	"Post ID"
+//	@Success		204	{string}	string	"Repost removed"
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/repost [delete]
+func (app *application) unrepostHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromCtx(r)
+	user := getUserFromCtx(r)
+
+	err := app.store.Posts.Unrepost(r.Context(), user.ID, originalPostID(post))
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err = app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// originalPostID returns the id of the post a repost points at, so reposts
+// and quotes always reference original content.
+func originalPostID(post *store.Post) int64 {
+	if post.RepostedPostID != nil {
+		return *post.RepostedPostID
+	}
+	return post.ID
+}
+
+// getEmbeddedPost loads the post a repost or quote points at together with
+// its author. A missing, deleted, unpublished or hidden post is not an error,
+// it is left out.
+func (app *application) getEmbeddedPost(r *http.Request, postID *int64) (*store.Post, error) {
+	if postID == nil {
+		return nil, nil
+	}
+
+	user := getUserFromCtx(r)
+	post, err := app.getVisiblePost(r.Context(), *postID, user.ID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if post.Status != store.StatusPublished {
+		return nil, nil
+	}
+
+	author, err := app.store.Users.GetUserByID(r.Context(), post.UserID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	post.User = store.User{ID: author.ID, Username: author.Username}
+
+	return post, nil
+}
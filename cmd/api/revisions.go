@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/lucianboboc/goBackendEngineering/internal/diff"
+	"github.com/lucianboboc/goBackendEngineering/internal/store"
+	"net/http"
+	"slices"
+	"strconv"
+)
+
+type PostRevisionsDiff struct {
+	From        int         `json:"from"`
+	To          int         `json:"to"`
+	Title       []diff.Edit `json:"title"`
+	Content     []diff.Edit `json:"content"`
+	TagsAdded   []string    `json:"tags_added"`
+	TagsRemoved []string    `json:"tags_removed"`
+}
+
+// getPostRevisionsHandler godoc
+//
+//	@Summary		Fetches the revisions of a post
+//	@Description	Fetches every recorded version of a post, newest first
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"Post ID"
+//	@Success		200	{object}	[]store.PostRevision
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/revisions [get]
+func (app *application) getPostRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromCtx(r)
+
+	revisions, err := app.store.Revisions.GetByPostID(r.Context(), post.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err = app.jsonResponse(w, http.StatusOK, revisions); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getPostRevisionsDiffHandler godoc
+//
+//	@Summary		Compares two versions of a post
+//	@Description	Word by word diff of the title and content and the tag changes between two versions
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int	true	"Post ID"
+//	@Param			from	query		int	true	"Older version"
+//	@Param			to		query		int	false	"Newer version, defaults to the current one"
+//	@Success		200		{object}	PostRevisionsDiff
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/revisions/diff [get]
+func (app *application) getPostRevisionsDiffHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromCtx(r)
+
+	from, err := strconv.Atoi(r.URL.Query().Get("from"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	to := post.Version
+	if v := r.URL.Query().Get("to"); v != "" {
+		to, err = strconv.Atoi(v)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+
+	older, err := app.getPostRevision(r.Context(), post, from)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	newer, err := app.getPostRevision(r.Context(), post, to)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	result := PostRevisionsDiff{
+		From:        older.Version,
+		To:          newer.Version,
+		Title:       diff.Words(older.Title, newer.Title),
+		Content:     diff.Words(older.Content, newer.Content),
+		TagsAdded:   make([]string, 0),
+		TagsRemoved: make([]string, 0),
+	}
+	for _, tag := range newer.Tags {
+		if !slices.Contains(older.Tags, tag) {
+			result.TagsAdded = append(result.TagsAdded, tag)
+		}
+	}
+	for _, tag := range older.Tags {
+		if !slices.Contains(newer.Tags, tag) {
+			result.TagsRemoved = append(result.TagsRemoved, tag)
+		}
+	}
+
+	if err = app.jsonResponse(w, http.StatusOK, result); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// revertPostHandler godoc
+//
+//	@Summary		Reverts a post to an older version
+//	@Description	Restores the title and content of an older version, with the hashtags they carry, as a new version of the post
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int	true	"Post ID"
+//	@Param			version	path		int	true	"Version to restore"
+//	@Success		200		{object}	store.Post
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/revisions/{version}/revert [post]
+func (app *application) revertPostHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromCtx(r)
+	user := getUserFromCtx(r)
+
+	if post.RepostedPostID != nil {
+		app.conflictResponse(w, r, errors.New("reposts cannot be reverted"))
+		return
+	}
+
+	version, err := strconv.Atoi(chi.URLParam(r, "version"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	revision, err := app.getPostRevision(r.Context(), post, version)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	post.Title = revision.Title
+	post.Content = revision.Content
+
+	err = app.store.Posts.UpdatePost(r.Context(), post, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.conflictResponse(w, r, errors.New("the post was changed while reverting it"))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err = app.jsonResponse(w, http.StatusOK, post); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getPostRevision returns the post as it was at the given version. Posts that
+// were never edited have no recorded revision, so their current version is
+// served from the post itself.
+func (app *application) getPostRevision(ctx context.Context, post *store.Post, version int) (*store.PostRevision, error) {
+	if version == post.Version {
+		return &store.PostRevision{
+			PostID:    post.ID,
+			Version:   post.Version,
+			Title:     post.Title,
+			Content:   post.Content,
+			Tags:      post.Tags,
+			CreatedAt: post.UpdatedAt,
+		}, nil
+	}
+
+	return app.store.Revisions.GetByVersion(ctx, post.ID, version)
+}
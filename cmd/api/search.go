@@ -0,0 +1,52 @@
+package main
+
+import (
+	"github.com/lucianboboc/goBackendEngineering/internal/store"
+	"net/http"
+)
+
+// searchHandler godoc
+//
+//	@Summary		Searches posts and comments
+//	@Description	Full-text search over post titles, contents and tags and over comments, best matches first
+//	@Tags			search
+//	@Accept			json
+//	@Produce		json
+//	@Param			q		query		string	true	"Search terms, supports quoted phrases, or and -exclusions"
+//	@Param			type	query		string	false	"all, posts or comments"
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			offset	query		int		false	"Offset"
+//	@Success		200		{object}	[]store.SearchResult
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/search [get]
+func (app *application) searchHandler(w http.ResponseWriter, r *http.Request) {
+	sq := store.SearchQuery{
+		Type:   "all",
+		Limit:  20,
+		Offset: 0,
+	}
+
+	sq, err := sq.Parse(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err = Validate.Struct(sq); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := getUserFromCtx(r)
+	results, err := app.store.Search.Search(r.Context(), user.ID, sq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err = app.jsonResponse(w, http.StatusOK, results); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
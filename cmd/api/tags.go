@@ -0,0 +1,138 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/lucianboboc/goBackendEngineering/internal/hashtag"
+	"github.com/lucianboboc/goBackendEngineering/internal/store"
+	"net/http"
+	"net/url"
+)
+
+type TagPage struct {
+	store.Tag
+	Posts []store.PostWithMetadata `json:"posts"`
+}
+
+// getTagHandler godoc
+//
+//	@Summary		Fetches a tag
+//	@Description	Fetches a tag with the number of posts carrying it and its most recent posts
+//	@Tags			tags
+//	@Accept			json
+//	@Produce		json
+//	@Param			tag		path		string	true	"Tag, with or without the leading #"
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			offset	query		int		false	"Offset"
+//	@Success		200		{object}	TagPage
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/tags/{tag} [get]
+func (app *application) getTagHandler(w http.ResponseWriter, r *http.Request) {
+	// a leading # reaches the router percent-encoded
+	param, err := url.PathUnescape(chi.URLParam(r, "tag"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	name, ok := hashtag.Normalize(param)
+	if !ok {
+		app.badRequestResponse(w, r, fmt.Errorf("invalid tag %q", param))
+		return
+	}
+
+	pq := store.PaginationQuery{
+		Limit:  20,
+		Offset: 0,
+	}
+
+	pq, err = pq.Parse(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err = Validate.Struct(pq); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	tag, err := app.store.Tags.GetByName(r.Context(), name)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	q := store.PaginatedPostsQuery{
+		Limit:    pq.Limit,
+		Offset:   pq.Offset,
+		Sort:     "newest",
+		Tags:     []string{tag.Name},
+		TagMatch: "any",
+	}
+
+	user := getUserFromCtx(r)
+	page := TagPage{Tag: *tag, Posts: make([]store.PostWithMetadata, 0, pq.Limit)}
+	err = app.store.Posts.GetAllPosts(r.Context(), user.ID, q, func(post *store.PostWithMetadata) error {
+		page.Posts = append(page.Posts, *post)
+		return nil
+	})
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err = app.jsonResponse(w, http.StatusOK, page); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// autocompleteTagsHandler godoc
+//
+//	@Summary		Autocompletes tags
+//	@Description	Fetches the tags in use starting with a prefix, most used first
+//	@Tags			tags
+//	@Accept			json
+//	@Produce		json
+//	@Param			prefix	query		string	true	"Start of the tag"
+//	@Param			limit	query		int		false	"Limit"
+//	@Success		200		{object}	[]store.Tag
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/tags [get]
+func (app *application) autocompleteTagsHandler(w http.ResponseWriter, r *http.Request) {
+	tq := store.TagsQuery{
+		Limit: 10,
+	}
+
+	tq, err := tq.Parse(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err = Validate.Struct(tq); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	tags, err := app.store.Tags.Autocomplete(r.Context(), tq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err = app.jsonResponse(w, http.StatusOK, tags); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
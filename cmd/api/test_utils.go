@@ -4,11 +4,13 @@ import (
 	"github.com/lucianboboc/goBackendEngineering/internal/auth"
 	"github.com/lucianboboc/goBackendEngineering/internal/store"
 	"github.com/lucianboboc/goBackendEngineering/internal/store/cache"
+	"github.com/lucianboboc/goBackendEngineering/internal/views"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 )
 
 func newTestApplication(t *testing.T) *application {
@@ -24,6 +26,7 @@ func newTestApplication(t *testing.T) *application {
 		store:         mockStore,
 		cacheStorage:  mockCacheStore,
 		authenticator: testAuth,
+		views:         views.NewCounter(time.Minute),
 	}
 }
 
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"github.com/lucianboboc/goBackendEngineering/internal/store"
+	"net/http"
+)
+
+// getTrendingPostsHandler godoc
+//
+//	@Summary		Fetches the trending posts
+//	@Description	Fetches the public posts with the most recent comment activity and author follower growth. The ranking is refreshed periodically
+//	@Tags			trending
+//	@Accept			json
+//	@Produce		json
+//	@Param			limit	query		int	false	"Limit"
+//	@Param			offset	query		int	false	"Offset"
+//	@Success		200		{object}	[]store.PostWithMetadata
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/trending/posts [get]
+func (app *application) getTrendingPostsHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromCtx(r)
+
+	pq, ok := app.parseTrendingQuery(w, r)
+	if !ok {
+		return
+	}
+
+	posts, err := app.store.Trending.GetPosts(r.Context(), user.ID, pq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err = app.jsonResponse(w, http.StatusOK, posts); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getTrendingTagsHandler godoc
+//
+//	@Summary		Fetches the trending tags
+//	@Description	Fetches the tags of the trending posts, ranked by the scores of those posts. The ranking is refreshed periodically
+//	@Tags			trending
+//	@Accept			json
+//	@Produce		json
+//	@Param			limit	query		int	false	"Limit"
+//	@Param			offset	query		int	false	"Offset"
+//	@Success		200		{object}	[]store.TrendingTag
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/trending/tags [get]
+func (app *application) getTrendingTagsHandler(w http.ResponseWriter, r *http.Request) {
+	pq, ok := app.parseTrendingQuery(w, r)
+	if !ok {
+		return
+	}
+
+	tags, err := app.store.Trending.GetTags(r.Context(), pq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err = app.jsonResponse(w, http.StatusOK, tags); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// parseTrendingQuery reads the page of a trending list, answering with a bad
+// request when it is invalid.
+func (app *application) parseTrendingQuery(w http.ResponseWriter, r *http.Request) (store.PaginationQuery, bool) {
+	pq := store.PaginationQuery{
+		Limit:  20,
+		Offset: 0,
+	}
+
+	pq, err := pq.Parse(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return pq, false
+	}
+
+	if err = Validate.Struct(pq); err != nil {
+		app.badRequestResponse(w, r, err)
+		return pq, false
+	}
+
+	return pq, true
+}
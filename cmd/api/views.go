@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"github.com/lucianboboc/goBackendEngineering/internal/store"
+	"net/http"
+	"strconv"
+)
+
+// recordView counts a view of the post by the user making the request. Authors
+// viewing their own posts are not counted.
+func (app *application) recordView(r *http.Request, post *store.Post) {
+	user := getUserFromCtx(r)
+	if post.UserID == user.ID {
+		return
+	}
+	app.views.Record(post.ID, user.ID)
+}
+
+// getPostViewsHandler godoc
+//
+//	@Summary		Fetches the views of a post
+//	@Description	Fetches the daily views of a post over the last days, oldest first. Views are counted once per viewer within a time window and show up after a short delay
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int	true	"Post ID"
+//	@Param			days	query		int	false	"Number of days, 30 by default and at most 365"
+//	@Success		200		{object}	[]store.ViewCount
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/views [get]
+func (app *application) getPostViewsHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromCtx(r)
+
+	days := 30
+	if v := r.URL.Query().Get("days"); v != "" {
+		d, err := strconv.Atoi(v)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+		days = d
+	}
+
+	if days < 1 || days > 365 {
+		app.badRequestResponse(w, r, errors.New("days must be between 1 and 365"))
+		return
+	}
+
+	counts, err := app.store.Views.GetDaily(r.Context(), post.ID, days)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err = app.jsonResponse(w, http.StatusOK, counts); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
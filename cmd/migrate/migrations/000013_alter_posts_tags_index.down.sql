@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_posts_tags;
+
+CREATE INDEX IF NOT EXISTS idx_posts_tags ON posts (tags);
\ No newline at end of file
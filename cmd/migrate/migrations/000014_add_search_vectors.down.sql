@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_comments_search_vector;
+
+DROP INDEX IF EXISTS idx_posts_search_vector;
+
+ALTER TABLE comments DROP COLUMN IF EXISTS search_vector;
+
+ALTER TABLE posts DROP COLUMN IF EXISTS search_vector;
+
+DROP FUNCTION IF EXISTS posts_tags_to_text(text[]);
\ No newline at end of file
@@ -0,0 +1,23 @@
+CREATE OR REPLACE FUNCTION posts_tags_to_text(tags text[]) RETURNS text
+    LANGUAGE sql IMMUTABLE
+AS $$ SELECT array_to_string(tags, ' ') $$;
+
+ALTER TABLE
+    posts
+ADD COLUMN
+    search_vector tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+        setweight(to_tsvector('english', coalesce(posts_tags_to_text(tags), '')), 'B') ||
+        setweight(to_tsvector('english', coalesce(content, '')), 'C')
+    ) STORED;
+
+ALTER TABLE
+    comments
+ADD COLUMN
+    search_vector tsvector GENERATED ALWAYS AS (
+        to_tsvector('english', coalesce(content, ''))
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_posts_search_vector ON posts USING GIN (search_vector);
+
+CREATE INDEX IF NOT EXISTS idx_comments_search_vector ON comments USING GIN (search_vector);
\ No newline at end of file
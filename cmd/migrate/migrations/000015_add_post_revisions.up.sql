@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS post_revisions(
+    post_id bigint NOT NULL,
+    version integer NOT NULL,
+    title text NOT NULL,
+    content text NOT NULL,
+    tags text[] NOT NULL,
+    edited_by bigint,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (post_id, version),
+    FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE,
+    FOREIGN KEY (edited_by) REFERENCES users (id) ON DELETE SET NULL
+);
\ No newline at end of file
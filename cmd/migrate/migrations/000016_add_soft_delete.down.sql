@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_comments_deleted_at;
+
+DROP INDEX IF EXISTS idx_posts_deleted_at;
+
+ALTER TABLE comments DROP COLUMN IF EXISTS deleted_at, DROP COLUMN IF EXISTS deleted_by;
+
+ALTER TABLE posts DROP COLUMN IF EXISTS deleted_at, DROP COLUMN IF EXISTS deleted_by;
\ No newline at end of file
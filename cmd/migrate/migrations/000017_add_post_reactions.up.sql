@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS post_reactions(
+    post_id bigint NOT NULL,
+    user_id bigint NOT NULL,
+    type varchar(20) NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (post_id, user_id),
+    FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_reactions_post_id_type ON post_reactions (post_id, type);
\ No newline at end of file
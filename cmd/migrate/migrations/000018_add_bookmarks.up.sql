@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS bookmarks(
+    user_id bigint NOT NULL,
+    post_id bigint NOT NULL,
+    created_at timestamp with time zone NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (user_id, post_id),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_bookmarks_user_id_created_at ON bookmarks (user_id, created_at DESC, post_id DESC);
\ No newline at end of file
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_posts_reposted_post_id;
+
+DROP INDEX IF EXISTS idx_posts_user_id_reposted_post_id;
+
+ALTER TABLE posts DROP COLUMN IF EXISTS quoted_post_id, DROP COLUMN IF EXISTS reposted_post_id;
\ No newline at end of file
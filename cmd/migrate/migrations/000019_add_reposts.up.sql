@@ -0,0 +1,11 @@
+ALTER TABLE
+    posts
+ADD COLUMN
+    reposted_post_id bigint REFERENCES posts (id) ON DELETE CASCADE,
+ADD COLUMN
+    quoted_post_id bigint REFERENCES posts (id) ON DELETE SET NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_posts_user_id_reposted_post_id ON posts (user_id, reposted_post_id)
+WHERE reposted_post_id IS NOT NULL AND deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_posts_reposted_post_id ON posts (reposted_post_id) WHERE reposted_post_id IS NOT NULL;
\ No newline at end of file
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS attachments(
+    id bigserial PRIMARY KEY,
+    post_id bigint NOT NULL,
+    storage_key text NOT NULL UNIQUE,
+    filename varchar(255) NOT NULL,
+    content_type varchar(255) NOT NULL,
+    size bigint NOT NULL,
+    checksum char(64) NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+    FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachments_post_id ON attachments (post_id);
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE comments DROP COLUMN IF EXISTS content_html;
+
+ALTER TABLE posts DROP COLUMN IF EXISTS content_html;
\ No newline at end of file
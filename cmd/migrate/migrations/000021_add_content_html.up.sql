@@ -0,0 +1,4 @@
+-- NULL marks content written before rendering existed, it is rendered when read
+ALTER TABLE posts ADD COLUMN content_html text;
+
+ALTER TABLE comments ADD COLUMN content_html text;
\ No newline at end of file
@@ -0,0 +1,9 @@
+DROP TRIGGER IF EXISTS posts_count_tags_update ON posts;
+
+DROP TRIGGER IF EXISTS posts_count_tags_insert_delete ON posts;
+
+DROP FUNCTION IF EXISTS posts_count_tags();
+
+DROP TABLE IF EXISTS tags;
+
+ALTER TABLE posts DROP COLUMN IF EXISTS explicit_tags;
\ No newline at end of file
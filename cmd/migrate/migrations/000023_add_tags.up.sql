@@ -0,0 +1,68 @@
+CREATE TABLE IF NOT EXISTS tags(
+    name varchar(50) PRIMARY KEY,
+    usage_count int NOT NULL DEFAULT 0,
+    last_used_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+-- text_pattern_ops lets the autocomplete prefix search use the index
+CREATE INDEX IF NOT EXISTS idx_tags_name_pattern ON tags (name text_pattern_ops);
+
+ALTER TABLE
+    posts
+ADD COLUMN
+    explicit_tags text[] NOT NULL DEFAULT '{}';
+
+-- bring the legacy free-text tags to the form hashtag.Normalize produces:
+-- without the leading "#", lower cased, NFKC normalized and deduplicated
+UPDATE posts SET tags = ARRAY(
+    SELECT n.name
+    FROM unnest(tags) WITH ORDINALITY AS t(name, i),
+        LATERAL (SELECT normalize(lower(regexp_replace(btrim(t.name), '^#', '')), NFKC) AS name) AS n
+    WHERE n.name <> ''
+    GROUP BY n.name
+    ORDER BY MIN(t.i)
+)
+WHERE tags <> '{}';
+
+-- a tag is explicit when the title and content carry no #hashtag for it, the
+-- legacy free-text tags are escaped so "c++" or "(" match literally
+UPDATE posts SET explicit_tags = ARRAY(
+    SELECT t FROM unnest(tags) AS t
+    WHERE NOT (title || ' ' || content) ~* ('(^|[^[:alnum:]_])#' || regexp_replace(t, '([.^$*+?()\[\]{}|\\])', '\\\1', 'g') || '($|[^[:alnum:]_])')
+)
+WHERE tags <> '{}';
+
+INSERT INTO tags (name, usage_count, last_used_at)
+SELECT t.name, COUNT(*), MAX(p.created_at)
+FROM posts AS p, unnest(p.tags) AS t(name)
+WHERE p.deleted_at IS NULL AND length(t.name) BETWEEN 1 AND 50
+GROUP BY t.name
+ON CONFLICT (name) DO NOTHING;
+
+-- usage_count is the number of posts that are not deleted carrying the tag
+CREATE OR REPLACE FUNCTION posts_count_tags() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') AND OLD.deleted_at IS NULL THEN
+        UPDATE tags SET usage_count = usage_count - 1
+        WHERE name = ANY(OLD.tags);
+    END IF;
+
+    IF TG_OP IN ('INSERT', 'UPDATE') AND NEW.deleted_at IS NULL THEN
+        INSERT INTO tags (name, usage_count, last_used_at)
+        SELECT DISTINCT t.name, 1, NOW() FROM unnest(NEW.tags) AS t(name)
+        ON CONFLICT (name) DO UPDATE SET usage_count = tags.usage_count + 1, last_used_at = NOW();
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER posts_count_tags_insert_delete
+AFTER INSERT OR DELETE ON posts
+FOR EACH ROW EXECUTE FUNCTION posts_count_tags();
+
+CREATE TRIGGER posts_count_tags_update
+AFTER UPDATE OF tags, deleted_at ON posts
+FOR EACH ROW
+WHEN (OLD.tags IS DISTINCT FROM NEW.tags OR OLD.deleted_at IS DISTINCT FROM NEW.deleted_at)
+EXECUTE FUNCTION posts_count_tags();
\ No newline at end of file
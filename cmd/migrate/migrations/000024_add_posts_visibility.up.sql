@@ -0,0 +1,32 @@
+ALTER TABLE
+    posts
+ADD COLUMN
+    visibility varchar(20) NOT NULL DEFAULT 'public'
+    CONSTRAINT posts_visibility_check CHECK (visibility IN ('public', 'followers', 'mentioned', 'private'));
+
+-- only public posts count towards tag usage, the others must not show up in
+-- the autocomplete or the counts
+CREATE OR REPLACE FUNCTION posts_count_tags() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') AND OLD.deleted_at IS NULL AND OLD.visibility = 'public' THEN
+        UPDATE tags SET usage_count = usage_count - 1
+        WHERE name = ANY(OLD.tags);
+    END IF;
+
+    IF TG_OP IN ('INSERT', 'UPDATE') AND NEW.deleted_at IS NULL AND NEW.visibility = 'public' THEN
+        INSERT INTO tags (name, usage_count, last_used_at)
+        SELECT DISTINCT t.name, 1, NOW() FROM unnest(NEW.tags) AS t(name)
+        ON CONFLICT (name) DO UPDATE SET usage_count = tags.usage_count + 1, last_used_at = NOW();
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS posts_count_tags_update ON posts;
+
+CREATE TRIGGER posts_count_tags_update
+AFTER UPDATE OF tags, deleted_at, visibility ON posts
+FOR EACH ROW
+WHEN (OLD.tags IS DISTINCT FROM NEW.tags OR OLD.deleted_at IS DISTINCT FROM NEW.deleted_at OR OLD.visibility IS DISTINCT FROM NEW.visibility)
+EXECUTE FUNCTION posts_count_tags();
@@ -0,0 +1,36 @@
+CREATE OR REPLACE FUNCTION posts_count_tags() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') AND OLD.deleted_at IS NULL AND OLD.visibility = 'public' THEN
+        UPDATE tags SET usage_count = usage_count - 1
+        WHERE name = ANY(OLD.tags);
+    END IF;
+
+    IF TG_OP IN ('INSERT', 'UPDATE') AND NEW.deleted_at IS NULL AND NEW.visibility = 'public' THEN
+        INSERT INTO tags (name, usage_count, last_used_at)
+        SELECT DISTINCT t.name, 1, NOW() FROM unnest(NEW.tags) AS t(name)
+        ON CONFLICT (name) DO UPDATE SET usage_count = tags.usage_count + 1, last_used_at = NOW();
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS posts_count_tags_update ON posts;
+
+CREATE TRIGGER posts_count_tags_update
+AFTER UPDATE OF tags, deleted_at, visibility ON posts
+FOR EACH ROW
+WHEN (OLD.tags IS DISTINCT FROM NEW.tags OR OLD.deleted_at IS DISTINCT FROM NEW.deleted_at OR OLD.visibility IS DISTINCT FROM NEW.visibility)
+EXECUTE FUNCTION posts_count_tags();
+
+DELETE FROM tags;
+
+INSERT INTO tags (name, usage_count, last_used_at)
+SELECT t.name, COUNT(*), MAX(p.created_at)
+FROM posts AS p, unnest(p.tags) AS t(name)
+WHERE p.deleted_at IS NULL AND p.visibility = 'public' AND length(t.name) BETWEEN 1 AND 50
+GROUP BY t.name;
+
+DROP INDEX IF EXISTS idx_posts_publish_at;
+
+ALTER TABLE posts DROP CONSTRAINT IF EXISTS posts_publish_at_check, DROP COLUMN IF EXISTS publish_at, DROP COLUMN IF EXISTS status;
\ No newline at end of file
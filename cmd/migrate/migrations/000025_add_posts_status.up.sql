@@ -0,0 +1,38 @@
+ALTER TABLE
+    posts
+ADD COLUMN
+    status varchar(20) NOT NULL DEFAULT 'published'
+    CONSTRAINT posts_status_check CHECK (status IN ('draft', 'scheduled', 'published')),
+ADD COLUMN
+    publish_at timestamp(0) with time zone,
+ADD CONSTRAINT
+    posts_publish_at_check CHECK (status <> 'scheduled' OR publish_at IS NOT NULL);
+
+-- the publisher only ever looks at scheduled posts that are due
+CREATE INDEX IF NOT EXISTS idx_posts_publish_at ON posts (publish_at) WHERE status = 'scheduled' AND deleted_at IS NULL;
+
+-- drafts and scheduled posts do not count towards tag usage until they are published
+CREATE OR REPLACE FUNCTION posts_count_tags() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') AND OLD.deleted_at IS NULL AND OLD.status = 'published' AND OLD.visibility = 'public' THEN
+        UPDATE tags SET usage_count = usage_count - 1
+        WHERE name = ANY(OLD.tags);
+    END IF;
+
+    IF TG_OP IN ('INSERT', 'UPDATE') AND NEW.deleted_at IS NULL AND NEW.status = 'published' AND NEW.visibility = 'public' THEN
+        INSERT INTO tags (name, usage_count, last_used_at)
+        SELECT DISTINCT t.name, 1, NOW() FROM unnest(NEW.tags) AS t(name)
+        ON CONFLICT (name) DO UPDATE SET usage_count = tags.usage_count + 1, last_used_at = NOW();
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS posts_count_tags_update ON posts;
+
+CREATE TRIGGER posts_count_tags_update
+AFTER UPDATE OF tags, deleted_at, status, visibility ON posts
+FOR EACH ROW
+WHEN (OLD.tags IS DISTINCT FROM NEW.tags OR OLD.deleted_at IS DISTINCT FROM NEW.deleted_at OR OLD.status IS DISTINCT FROM NEW.status OR OLD.visibility IS DISTINCT FROM NEW.visibility)
+EXECUTE FUNCTION posts_count_tags();
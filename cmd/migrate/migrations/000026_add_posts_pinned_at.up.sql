@@ -0,0 +1,6 @@
+ALTER TABLE
+    posts
+ADD COLUMN
+    pinned_at timestamp(0) with time zone;
+
+CREATE INDEX IF NOT EXISTS idx_posts_user_pinned_at ON posts (user_id, pinned_at) WHERE pinned_at IS NOT NULL;
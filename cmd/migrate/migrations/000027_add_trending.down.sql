@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_followers_created_at;
+
+DROP INDEX IF EXISTS idx_comments_created_at;
+
+DROP TABLE IF EXISTS trending_tags;
+
+DROP TABLE IF EXISTS trending_posts;
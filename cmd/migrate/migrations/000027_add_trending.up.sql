@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS trending_posts (
+    post_id bigint PRIMARY KEY REFERENCES posts (id) ON DELETE CASCADE,
+    score double precision NOT NULL,
+    computed_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_trending_posts_score ON trending_posts (score DESC, post_id DESC);
+
+CREATE TABLE IF NOT EXISTS trending_tags (
+    name varchar(50) PRIMARY KEY,
+    score double precision NOT NULL,
+    post_count int NOT NULL,
+    computed_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_trending_tags_score ON trending_tags (score DESC, name);
+
+CREATE INDEX IF NOT EXISTS idx_comments_created_at ON comments (created_at);
+
+CREATE INDEX IF NOT EXISTS idx_followers_created_at ON followers (created_at);
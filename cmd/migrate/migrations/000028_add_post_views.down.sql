@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS post_views_daily;
+
+ALTER TABLE posts DROP COLUMN IF EXISTS view_count;
@@ -0,0 +1,12 @@
+ALTER TABLE
+    posts
+ADD COLUMN
+    view_count bigint NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS post_views_daily (
+    post_id bigint NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+    day date NOT NULL,
+    views bigint NOT NULL DEFAULT 0,
+
+    PRIMARY KEY (post_id, day)
+);
@@ -0,0 +1,13 @@
+ALTER TABLE
+    comments
+DROP CONSTRAINT IF EXISTS fk_comments_user,
+DROP CONSTRAINT IF EXISTS fk_comments_post;
+
+CREATE SEQUENCE IF NOT EXISTS comments_post_id_seq OWNED BY comments.post_id;
+
+CREATE SEQUENCE IF NOT EXISTS comments_user_id_seq OWNED BY comments.user_id;
+
+ALTER TABLE
+    comments
+ALTER COLUMN post_id SET DEFAULT nextval('comments_post_id_seq'),
+ALTER COLUMN user_id SET DEFAULT nextval('comments_user_id_seq');
@@ -0,0 +1,20 @@
+ALTER TABLE
+    comments
+ALTER COLUMN post_id DROP DEFAULT,
+ALTER COLUMN user_id DROP DEFAULT;
+
+DROP SEQUENCE IF EXISTS comments_post_id_seq;
+
+DROP SEQUENCE IF EXISTS comments_user_id_seq;
+
+-- replies to the removed comments go with them through parent_id
+DELETE FROM comments AS c
+WHERE NOT EXISTS (SELECT 1 FROM posts AS p WHERE p.id = c.post_id)
+OR NOT EXISTS (SELECT 1 FROM users AS u WHERE u.id = c.user_id);
+
+ALTER TABLE
+    comments
+ADD
+    CONSTRAINT fk_comments_post FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE,
+ADD
+    CONSTRAINT fk_comments_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
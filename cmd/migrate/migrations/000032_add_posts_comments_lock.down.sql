@@ -0,0 +1,5 @@
+ALTER TABLE
+    posts
+DROP COLUMN IF EXISTS lock_reason,
+DROP COLUMN IF EXISTS locked_by,
+DROP COLUMN IF EXISTS comments_locked;
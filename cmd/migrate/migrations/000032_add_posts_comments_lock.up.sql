@@ -0,0 +1,8 @@
+ALTER TABLE
+    posts
+ADD COLUMN
+    comments_locked boolean NOT NULL DEFAULT false,
+ADD COLUMN
+    locked_by bigint REFERENCES users (id) ON DELETE SET NULL,
+ADD COLUMN
+    lock_reason text;
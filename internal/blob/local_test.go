@@ -0,0 +1,55 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLocalStore(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("should read back what was written", func(t *testing.T) {
+		if err := s.Put(ctx, "attachments/a", strings.NewReader("hello")); err != nil {
+			t.Fatal(err)
+		}
+
+		rc, err := s.Get(ctx, "attachments/a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rc.Close()
+
+		b, err := io.ReadAll(rc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != "hello" {
+			t.Errorf("expected %q, got %q", "hello", b)
+		}
+	})
+
+	t.Run("should return ErrNotFound for a deleted blob", func(t *testing.T) {
+		if err := s.Delete(ctx, "attachments/a"); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := s.Get(ctx, "attachments/a"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("should reject keys outside the root", func(t *testing.T) {
+		for _, key := range []string{"", "/etc/passwd", "../a", "a/../../b", ".."} {
+			if err := s.Put(ctx, key, strings.NewReader("x")); !errors.Is(err, ErrInvalidKey) {
+				t.Errorf("key %q: expected ErrInvalidKey, got %v", key, err)
+			}
+		}
+	})
+}
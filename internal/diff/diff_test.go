@@ -0,0 +1,55 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWords(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []Edit
+	}{
+		{
+			name: "identical texts",
+			a:    "hello gophers",
+			b:    "hello gophers",
+			want: []Edit{{Op: Equal, Text: "hello gophers"}},
+		},
+		{
+			name: "replaced word",
+			a:    "hello gophers",
+			b:    "hello rustaceans",
+			want: []Edit{
+				{Op: Equal, Text: "hello "},
+				{Op: Delete, Text: "gophers"},
+				{Op: Insert, Text: "rustaceans"},
+			},
+		},
+		{
+			name: "appended words",
+			a:    "hello",
+			b:    "hello there gophers",
+			want: []Edit{
+				{Op: Equal, Text: "hello"},
+				{Op: Insert, Text: " there gophers"},
+			},
+		},
+		{
+			name: "empty before",
+			a:    "",
+			b:    "new post",
+			want: []Edit{{Op: Insert, Text: "new post"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Words(tt.a, tt.b)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Words(%q, %q) = %+v, want %+v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package hashtag
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxLength is the longest tag kept, in Unicode code points.
+const MaxLength = 50
+
+var folder = cases.Fold()
+
+// Normalize returns the canonical form of a tag: without the leading "#",
+// NFKC normalized and case folded, so "#Café", "café" and "CAFÉ" are the same
+// tag. Tags may hold letters, marks, digits and "_" only; ok is false for
+// anything else, for empty tags and for tags longer than MaxLength.
+func Normalize(tag string) (string, bool) {
+	tag = strings.TrimPrefix(strings.TrimSpace(tag), "#")
+	tag = norm.NFKC.String(folder.String(tag))
+
+	if tag == "" || utf8.RuneCountInString(tag) > MaxLength {
+		return "", false
+	}
+
+	for _, r := range tag {
+		if !isTagRune(r) {
+			return "", false
+		}
+	}
+
+	return tag, true
+}
+
+// Extract returns the normalized #hashtags found in the texts, in order of
+// appearance and without duplicates. A "#" only starts a hashtag at the
+// beginning of a word, so "C#" or "page#anchor" are not tags.
+func Extract(texts ...string) []string {
+	tags := make([]string, 0)
+	seen := make(map[string]bool)
+
+	for _, text := range texts {
+		prev := ' '
+		for i, r := range text {
+			if r == '#' && !isTagRune(prev) && prev != '#' {
+				end := i + 1
+				for end < len(text) {
+					next, size := utf8.DecodeRuneInString(text[end:])
+					if !isTagRune(next) {
+						break
+					}
+					end += size
+				}
+
+				if tag, ok := Normalize(text[i:end]); ok && !seen[tag] {
+					seen[tag] = true
+					tags = append(tags, tag)
+				}
+			}
+			prev = r
+		}
+	}
+
+	return tags
+}
+
+// Merge normalizes the explicit tags, drops the invalid ones and appends the
+// hashtags found in the texts that are not already present.
+func Merge(tags []string, texts ...string) []string {
+	return merge(tags, false, texts)
+}
+
+// MergeStored is Merge for explicit tags that were already stored. Legacy
+// free-text tags that don't normalize are kept as they are rather than lost on
+// the next edit, as long as they fit in MaxLength.
+func MergeStored(tags []string, texts ...string) []string {
+	return merge(tags, true, texts)
+}
+
+func merge(tags []string, keepInvalid bool, texts []string) []string {
+	merged := make([]string, 0, len(tags))
+	seen := make(map[string]bool)
+
+	add := func(tag string) {
+		if !seen[tag] {
+			seen[tag] = true
+			merged = append(merged, tag)
+		}
+	}
+
+	for _, t := range tags {
+		if tag, ok := Normalize(t); ok {
+			add(tag)
+		} else if keepInvalid && t != "" && utf8.RuneCountInString(t) <= MaxLength {
+			add(t)
+		}
+	}
+
+	for _, tag := range Extract(texts...) {
+		add(tag)
+	}
+
+	return merged
+}
+
+func isTagRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+}
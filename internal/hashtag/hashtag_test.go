@@ -0,0 +1,57 @@
+package hashtag
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+		ok   bool
+	}{
+		{"#GoLang", "golang", true},
+		{"CAFÉ", "café", true},
+		{"Café", "café", true},
+		{"Straße", "strasse", true},
+		{"ｇｏ", "go", true},
+		{"go_lang", "go_lang", true},
+		{"", "", false},
+		{"#", "", false},
+		{"go-lang", "", false},
+		{strings.Repeat("a", MaxLength+1), "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := Normalize(tt.tag)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Normalize(%q) = %q, %v, want %q, %v", tt.tag, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestExtract(t *testing.T) {
+	got := Extract("Learning #Go and #golang.", "more #go, C# and page#anchor, ##double #日本語")
+	want := []string{"go", "golang", "日本語"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Extract() = %v, want %v", got, want)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	got := Merge([]string{"Go", "bad tag", "go"}, "about #Go and #Postgres")
+	want := []string{"go", "postgres"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Merge() = %v, want %v", got, want)
+	}
+}
+
+func TestMergeStored(t *testing.T) {
+	got := MergeStored([]string{"Go", "c++", "go", strings.Repeat("+", MaxLength+1)}, "about #Go and #Postgres")
+	want := []string{"go", "c++", "postgres"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MergeStored() = %v, want %v", got, want)
+	}
+}
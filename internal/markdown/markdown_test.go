@@ -0,0 +1,26 @@
+package markdown
+
+import "testing"
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"emphasis", "*hi* **there**", "<p><em>hi</em> <strong>there</strong></p>\n"},
+		{"code block", "```go\nfmt.Println()\n```", "<pre><code class=\"language-go\">fmt.Println()\n</code></pre>\n"},
+		{"raw html is dropped", "<script>alert(1)</script>", "\n"},
+		{"inline html is dropped", "a <img src=x onerror=alert(1)> b", "<p>a  b</p>\n"},
+		{"javascript links are dropped", "[x](javascript:alert(1))", "<p>x</p>\n"},
+		{"links", "[go](https://go.dev)", "<p><a href=\"https://go.dev\" rel=\"nofollow noreferrer noopener\" target=\"_blank\">go</a></p>\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Render(tt.source); got != tt.want {
+				t.Errorf("Render(%q) = %q, want %q", tt.source, got, tt.want)
+			}
+		})
+	}
+}
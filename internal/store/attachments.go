@@ -0,0 +1,104 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Attachment is the metadata of a file uploaded with a post. The file itself
+// lives in a blob.Store under Key.
+type Attachment struct {
+	ID          int64      `json:"id"`
+	PostID      int64      `json:"post_id"`
+	Key         string     `json:"-"`
+	Filename    string     `json:"filename"`
+	ContentType string     `json:"content_type"`
+	Size        int64      `json:"size"`
+	Checksum    string     `json:"checksum"`
+	CreatedAt   *time.Time `json:"created_at"`
+}
+
+type AttachmentsStore struct {
+	db *sql.DB
+}
+
+func (s *AttachmentsStore) GetByPostID(ctx context.Context, postID int64) ([]Attachment, error) {
+	query := `SELECT id, post_id, storage_key, filename, content_type, size, checksum, created_at
+	FROM attachments
+	WHERE post_id = $1
+	ORDER BY id`
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments := make([]Attachment, 0)
+	for rows.Next() {
+		var a Attachment
+		err = rows.Scan(&a.ID, &a.PostID, &a.Key, &a.Filename, &a.ContentType, &a.Size, &a.Checksum, &a.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		attachments = append(attachments, a)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
+
+// GetByID returns the attachment unless the post it belongs to was deleted or
+// is not visible to the viewer.
+func (s *AttachmentsStore) GetByID(ctx context.Context, id, viewerID int64) (*Attachment, error) {
+	query := `SELECT a.id, a.post_id, a.storage_key, a.filename, a.content_type, a.size, a.checksum, a.created_at
+	FROM attachments AS a
+	JOIN posts AS p ON p.id = a.post_id
+	WHERE a.id = $1 AND p.deleted_at IS NULL AND ` + visibleTo("p", "$2")
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+
+	var a Attachment
+	err := s.db.QueryRowContext(ctx, query, id, viewerID).Scan(
+		&a.ID,
+		&a.PostID,
+		&a.Key,
+		&a.Filename,
+		&a.ContentType,
+		&a.Size,
+		&a.Checksum,
+		&a.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &a, nil
+}
+
+func (s *AttachmentsStore) create(ctx context.Context, tx *sql.Tx, a *Attachment) error {
+	query := `INSERT INTO attachments (post_id, storage_key, filename, content_type, size, checksum)
+	VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at`
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+
+	return tx.QueryRowContext(ctx, query, a.PostID, a.Key, a.Filename, a.ContentType, a.Size, a.Checksum).Scan(
+		&a.ID,
+		&a.CreatedAt,
+	)
+}
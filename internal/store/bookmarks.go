@@ -0,0 +1,94 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type BookmarksStore struct {
+	db *sql.DB
+}
+
+// Add saves the post for the user. Saving an already saved post is a no-op.
+func (s *BookmarksStore) Add(ctx context.Context, userID, postID int64) error {
+	query := `INSERT INTO bookmarks (user_id, post_id) VALUES ($1, $2)
+	ON CONFLICT (user_id, post_id) DO NOTHING`
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, userID, postID)
+	return err
+}
+
+func (s *BookmarksStore) Remove(ctx context.Context, userID, postID int64) error {
+	query := `DELETE FROM bookmarks WHERE user_id = $1 AND post_id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, userID, postID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *BookmarksStore) Exists(ctx context.Context, userID, postID int64) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM bookmarks WHERE user_id = $1 AND post_id = $2)`
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+
+	var exists bool
+	err := s.db.QueryRowContext(ctx, query, userID, postID).Scan(&exists)
+	return exists, err
+}
+
+// GetByUser returns the posts saved by the user, most recently saved first.
+// Bookmarks saved at the same instant keep a stable order through post_id so
+// pages don't overlap.
+func (s *BookmarksStore) GetByUser(ctx context.Context, userID int64, p PaginationQuery) ([]PostWithMetadata, error) {
+	query := fmt.Sprintf(`SELECT %s
+	FROM bookmarks AS b
+	JOIN posts AS p ON p.id = b.post_id
+	JOIN users AS u ON p.user_id = u.id
+	WHERE b.user_id = $1 AND p.deleted_at IS NULL AND p.status = 'published' AND `+visibleTo("p", "$1")+`
+	ORDER BY b.created_at DESC, b.post_id DESC
+	LIMIT $2 OFFSET $3`, postWithMetadataColumns("$1"))
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID, p.Limit, p.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := make([]PostWithMetadata, 0)
+	for rows.Next() {
+		var post PostWithMetadata
+		if err = scanPostWithMetadata(rows, &post); err != nil {
+			return nil, err
+		}
+
+		posts = append(posts, post)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
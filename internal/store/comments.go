@@ -3,81 +3,327 @@ package store
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
 	"time"
+
+	"github.com/lib/pq"
+	"github.com/lucianboboc/goBackendEngineering/internal/markdown"
 )
 
+// maxThreadDepth is how many levels of replies are loaded at once. Deeper
+// replies are fetched with GetReplies, ReplyCount tells when there are any.
+const maxThreadDepth = 8
+
 type Comment struct {
-	ID        int64      `json:"id"`
-	PostID    int64      `json:"post_id"`
-	UserID    int64      `json:"user_id"`
-	Content   string     `json:"content"`
-	CreatedAt *time.Time `json:"created_at"`
-	User      User       `json:"user"`
+	ID          int64      `json:"id"`
+	PostID      int64      `json:"post_id"`
+	ParentID    *int64     `json:"parent_id"`
+	UserID      int64      `json:"user_id"`
+	Content     string     `json:"content"`
+	ContentHTML string     `json:"content_html"`
+	Mentions    []Mention  `json:"mentions"`
+	CreatedAt   *time.Time `json:"created_at"`
+	EditedAt    *time.Time `json:"edited_at"`
+	User        User       `json:"user"`
+	ReplyCount  int        `json:"reply_count"`
+	Replies     []Comment  `json:"replies"`
 }
 
 type CommentsStore struct {
 	db *sql.DB
 }
 
-func (c *CommentsStore) GetByPostID(ctx context.Context, postID int64) ([]Comment, error) {
-	query := `SELECT c.id, c.post_id, c.user_id, c.content, c.created_at, users.username, users.id, users.email FROM comments AS c
-	JOIN users ON users.id = c.user_id
-	WHERE c.post_id = $1
-	ORDER BY c.created_at DESC`
+// GetByPostID returns a page of the top level comments of the post in the
+// order of q.Sort, each with its replies nested oldest first. When a cursor is
+// given the page starts right after it.
+func (c *CommentsStore) GetByPostID(ctx context.Context, postID int64, q CommentsQuery) ([]Comment, error) {
+	direction, comparison := "DESC", "<"
+	if q.Sort == "oldest" {
+		direction, comparison = "ASC", ">"
+	}
+
+	roots := fmt.Sprintf(`SELECT id, post_id, parent_id, user_id, content, content_html, created_at, edited_at
+		FROM comments
+		WHERE post_id = $1 AND parent_id IS NULL AND deleted_at IS NULL
+			AND ($2::timestamptz IS NULL OR (created_at, id) %[2]s ($2, $3))
+		ORDER BY created_at %[1]s, id %[1]s
+		LIMIT $4`, direction, comparison)
+
+	var (
+		cursorCreatedAt *time.Time
+		cursorID        int64
+	)
+	if q.Cursor != nil {
+		cursorCreatedAt = &q.Cursor.CreatedAt
+		cursorID = q.Cursor.ID
+	}
+
+	comments, err := c.getThread(ctx, roots, postID, cursorCreatedAt, cursorID, q.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	if q.Sort != "oldest" {
+		slices.Reverse(comments)
+	}
+	return comments, nil
+}
+
+// Count returns how many top level comments the post has.
+func (c *CommentsStore) Count(ctx context.Context, postID int64) (int, error) {
+	query := `SELECT COUNT(*) FROM comments WHERE post_id = $1 AND parent_id IS NULL AND deleted_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+
+	var count int
+	err := c.db.QueryRowContext(ctx, query, postID).Scan(&count)
+	return count, err
+}
+
+// GetReplies returns the replies to the comment as a tree, oldest first.
+func (c *CommentsStore) GetReplies(ctx context.Context, postID, commentID int64) ([]Comment, error) {
+	roots := `SELECT id, post_id, parent_id, user_id, content, content_html, created_at, edited_at
+		FROM comments
+		WHERE post_id = $1 AND parent_id = $2 AND deleted_at IS NULL`
+
+	return c.getThread(ctx, roots, postID, commentID)
+}
+
+func (c *CommentsStore) GetByID(ctx context.Context, id int64) (*Comment, error) {
+	query := `SELECT id, post_id, parent_id, user_id, content, content_html, created_at, edited_at,
+	` + mentionsJSON("comments.post_id", "comments.id") + `
+	FROM comments WHERE id = $1 AND deleted_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+
+	var comment Comment
+	var contentHTML sql.NullString
+	var mentions []byte
+	err := c.db.QueryRowContext(ctx, query, id).Scan(
+		&comment.ID,
+		&comment.PostID,
+		&comment.ParentID,
+		&comment.UserID,
+		&comment.Content,
+		&contentHTML,
+		&comment.CreatedAt,
+		&comment.EditedAt,
+		&mentions,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	comment.ContentHTML = renderedContent(contentHTML, comment.Content)
+	if err = json.Unmarshal(mentions, &comment.Mentions); err != nil {
+		return nil, err
+	}
+
+	return &comment, nil
+}
+
+// getThread loads the comments selected by rootsQuery, called with args,
+// and their replies up to maxThreadDepth levels in a single query. The roots
+// are returned oldest first. The replies of a deleted comment are not shown.
+func (c *CommentsStore) getThread(ctx context.Context, rootsQuery string, args ...any) ([]Comment, error) {
+	query := `WITH RECURSIVE roots AS (
+		` + rootsQuery + `
+	), thread AS (
+		SELECT id, post_id, parent_id, user_id, content, content_html, created_at, edited_at, 1 AS depth
+		FROM roots
+		UNION ALL
+		SELECT c.id, c.post_id, c.parent_id, c.user_id, c.content, c.content_html, c.created_at, c.edited_at, t.depth + 1
+		FROM comments AS c
+		JOIN thread AS t ON c.parent_id = t.id
+		WHERE c.deleted_at IS NULL AND t.depth < ` + strconv.Itoa(maxThreadDepth) + `
+	)
+	SELECT t.id, t.post_id, t.parent_id, t.user_id, t.content, t.content_html, t.created_at, t.edited_at, t.depth,
+	(SELECT COUNT(*) FROM comments AS r WHERE r.parent_id = t.id AND r.deleted_at IS NULL) AS reply_count,
+	users.username, users.id, users.email,
+	` + mentionsJSON("t.post_id", "t.id") + `
+	FROM thread AS t
+	JOIN users ON users.id = t.user_id
+	ORDER BY t.depth, t.created_at, t.id`
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
 
-	rows, err := c.db.QueryContext(ctx, query, postID)
+	rows, err := c.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	comments := []Comment{}
+	var (
+		comments []Comment
+		roots    []int
+		children = make(map[int64][]int)
+	)
 	for rows.Next() {
 		c := Comment{}
 		c.User = User{}
+		var contentHTML sql.NullString
+		var mentions []byte
+		var depth int
 		err = rows.Scan(
 			&c.ID,
 			&c.PostID,
+			&c.ParentID,
 			&c.UserID,
 			&c.Content,
+			&contentHTML,
 			&c.CreatedAt,
+			&c.EditedAt,
+			&depth,
+			&c.ReplyCount,
 			&c.User.Username,
 			&c.User.ID,
 			&c.User.Email,
+			&mentions,
 		)
 		if err != nil {
 			return nil, err
 		}
+		c.ContentHTML = renderedContent(contentHTML, c.Content)
+		if err = json.Unmarshal(mentions, &c.Mentions); err != nil {
+			return nil, err
+		}
 
+		if depth == 1 {
+			roots = append(roots, len(comments))
+		} else {
+			children[*c.ParentID] = append(children[*c.ParentID], len(comments))
+		}
 		comments = append(comments, c)
 	}
 	if err = rows.Err(); err != nil {
 		return nil, err
 	}
 
-	return comments, nil
+	return buildThread(comments, roots, children), nil
+}
+
+// buildThread nests the comments at the given indexes with their replies.
+// children maps a comment id to the indexes of its replies.
+func buildThread(comments []Comment, indexes []int, children map[int64][]int) []Comment {
+	thread := make([]Comment, 0, len(indexes))
+	for _, i := range indexes {
+		c := comments[i]
+		c.Replies = buildThread(comments, children[c.ID], children)
+		thread = append(thread, c)
+	}
+	return thread
 }
 
+// Create inserts the comment and the users mentioned in it. A comment with a
+// ParentID is a reply to that comment. It returns ErrNotFound when the post,
+// the parent comment or the author no longer exist.
 func (c *CommentsStore) Create(ctx context.Context, comment *Comment) error {
-	query := `INSERT INTO comments (post_id, user_id, content) 
-	VALUES ($1, $2, $3)
-	RETURNING id, created_at`
+	mentions := &MentionsStore{c.db}
+
+	return withTx(c.db, ctx, func(tx *sql.Tx) error {
+		query := `INSERT INTO comments (post_id, parent_id, user_id, content, content_html)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+
+		comment.ContentHTML = markdown.Render(comment.Content)
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			comment.PostID,
+			comment.ParentID,
+			comment.UserID,
+			comment.Content,
+			comment.ContentHTML,
+		).Scan(
+			&comment.ID,
+			&comment.CreatedAt,
+		)
+		if err != nil {
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+				return ErrNotFound
+			}
+			return err
+		}
+		comment.Replies = []Comment{}
+
+		comment.Mentions, err = mentions.replace(ctx, tx, comment.PostID, &comment.ID, comment.Content)
+		return err
+	})
+}
+
+// Update saves the new content of the comment, marking it as edited, and the
+// users mentioned in it.
+func (c *CommentsStore) Update(ctx context.Context, comment *Comment) error {
+	mentions := &MentionsStore{c.db}
+
+	return withTx(c.db, ctx, func(tx *sql.Tx) error {
+		query := `UPDATE comments SET content = $1, content_html = $2, edited_at = NOW()
+		WHERE id = $3 AND deleted_at IS NULL
+		RETURNING edited_at`
+
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+
+		comment.ContentHTML = markdown.Render(comment.Content)
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			comment.Content,
+			comment.ContentHTML,
+			comment.ID,
+		).Scan(
+			&comment.EditedAt,
+		)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+
+		comment.Mentions, err = mentions.replace(ctx, tx, comment.PostID, &comment.ID, comment.Content)
+		return err
+	})
+}
+
+// Delete hides the comment, and with it its replies, until PurgeDeleted
+// removes it.
+func (c *CommentsStore) Delete(ctx context.Context, commentID, deletedBy int64) error {
+	query := `UPDATE comments SET deleted_at = NOW(), deleted_by = $2
+	WHERE id = $1 AND deleted_at IS NULL`
 
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	err := c.db.QueryRowContext(
-		ctx,
-		query,
-		comment.PostID,
-		comment.UserID,
-		comment.Content,
-	).Scan(
-		&comment.ID,
-		&comment.CreatedAt,
-	)
+	res, err := c.db.ExecContext(ctx, query, commentID, deletedBy)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
 	if err != nil {
 		return err
 	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
 	return nil
 }
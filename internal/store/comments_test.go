@@ -0,0 +1,34 @@
+package store
+
+import "testing"
+
+func TestBuildThread(t *testing.T) {
+	parent := func(id int64) *int64 { return &id }
+
+	// rows as getThread reads them, ordered by depth
+	comments := []Comment{
+		{ID: 1},
+		{ID: 2},
+		{ID: 3, ParentID: parent(1)},
+		{ID: 4, ParentID: parent(1)},
+		{ID: 5, ParentID: parent(3)},
+	}
+	children := map[int64][]int{1: {2, 3}, 3: {4}}
+
+	thread := buildThread(comments, []int{0, 1}, children)
+
+	if len(thread) != 2 || thread[0].ID != 1 || thread[1].ID != 2 {
+		t.Fatalf("expected comments 1 and 2 at the top level, got %+v", thread)
+	}
+
+	replies := thread[0].Replies
+	if len(replies) != 2 || replies[0].ID != 3 || replies[1].ID != 4 {
+		t.Fatalf("expected replies 3 and 4 to comment 1, got %+v", replies)
+	}
+	if len(replies[0].Replies) != 1 || replies[0].Replies[0].ID != 5 {
+		t.Errorf("expected reply 5 to comment 3, got %+v", replies[0].Replies)
+	}
+	if thread[1].Replies == nil || len(thread[1].Replies) != 0 {
+		t.Errorf("expected an empty list of replies to comment 2, got %+v", thread[1].Replies)
+	}
+}
@@ -0,0 +1,180 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"time"
+	"unicode/utf8"
+
+	"github.com/lib/pq"
+)
+
+// mentionPattern matches @username tokens that are not part of a word or an
+// email address.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@.])@(\w{1,100})`)
+
+// Mention is a @username token resolved to a user. Offset and Length count
+// Unicode code points in the content, starting at the "@".
+type Mention struct {
+	UserID   int64  `json:"user_id"`
+	Username string `json:"username"`
+	Offset   int    `json:"offset"`
+	Length   int    `json:"length"`
+}
+
+type MentionsStore struct {
+	db *sql.DB
+}
+
+// GetPostsByUser returns the posts mentioning the user in their content or in
+// one of their comments, most recently mentioned first.
+func (s *MentionsStore) GetPostsByUser(ctx context.Context, userID int64, p PaginationQuery) ([]PostWithMetadata, error) {
+	query := fmt.Sprintf(`SELECT %s
+	FROM (
+		SELECT m.post_id, MAX(m.created_at) AS mentioned_at
+		FROM mentions AS m
+		LEFT JOIN comments AS c ON c.id = m.comment_id
+		WHERE m.user_id = $1 AND (m.comment_id IS NULL OR c.deleted_at IS NULL)
+		GROUP BY m.post_id
+	) AS mp
+	JOIN posts AS p ON p.id = mp.post_id
+	JOIN users AS u ON p.user_id = u.id
+	WHERE p.deleted_at IS NULL AND p.status = 'published' AND `+visibleTo("p", "$1")+`
+	ORDER BY mp.mentioned_at DESC, p.id DESC
+	LIMIT $2 OFFSET $3`, postWithMetadataColumns("$1"))
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID, p.Limit, p.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := make([]PostWithMetadata, 0)
+	for rows.Next() {
+		var post PostWithMetadata
+		if err = scanPostWithMetadata(rows, &post); err != nil {
+			return nil, err
+		}
+
+		posts = append(posts, post)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+// replace resolves the mentions in content and stores them for the post, or
+// for the comment when commentID is set, dropping the ones saved before.
+// Tokens that match no user are left as plain text. A user mentioned before
+// the edit keeps the original mention time, so edits don't notify again.
+func (s *MentionsStore) replace(ctx context.Context, tx *sql.Tx, postID int64, commentID *int64, content string) ([]Mention, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+
+	query := `DELETE FROM mentions WHERE post_id = $1 AND comment_id IS NOT DISTINCT FROM $2
+	RETURNING user_id, created_at`
+	rows, err := tx.QueryContext(ctx, query, postID, commentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	mentionedAt := make(map[int64]time.Time)
+	for rows.Next() {
+		var userID int64
+		var createdAt time.Time
+		if err = rows.Scan(&userID, &createdAt); err != nil {
+			return nil, err
+		}
+		mentionedAt[userID] = createdAt
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	mentions := parseMentions(content)
+	if len(mentions) == 0 {
+		return mentions, nil
+	}
+
+	usernames := make([]string, 0, len(mentions))
+	for _, m := range mentions {
+		usernames = append(usernames, m.Username)
+	}
+
+	query = `SELECT id, username FROM users WHERE username = ANY($1)`
+	rows, err = tx.QueryContext(ctx, query, pq.Array(usernames))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	userIDs := make(map[string]int64)
+	for rows.Next() {
+		var id int64
+		var username string
+		if err = rows.Scan(&id, &username); err != nil {
+			return nil, err
+		}
+		userIDs[username] = id
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	resolved := make([]Mention, 0, len(mentions))
+	query = `INSERT INTO mentions (user_id, post_id, comment_id, start_offset, length, created_at)
+	VALUES ($1, $2, $3, $4, $5, COALESCE($6, NOW()))`
+	for _, m := range mentions {
+		id, ok := userIDs[m.Username]
+		if !ok {
+			continue
+		}
+		m.UserID = id
+
+		var createdAt *time.Time
+		if t, ok := mentionedAt[id]; ok {
+			createdAt = &t
+		}
+
+		if _, err = tx.ExecContext(ctx, query, m.UserID, postID, commentID, m.Offset, m.Length, createdAt); err != nil {
+			return nil, err
+		}
+		resolved = append(resolved, m)
+	}
+
+	return resolved, nil
+}
+
+// parseMentions finds the @username tokens in content. The users are not
+// resolved, so UserID is left empty.
+func parseMentions(content string) []Mention {
+	mentions := make([]Mention, 0)
+	for _, match := range mentionPattern.FindAllStringSubmatchIndex(content, -1) {
+		// match[2]:match[3] is the username, the "@" sits right before it
+		start, end := match[2]-1, match[3]
+		mentions = append(mentions, Mention{
+			Username: content[match[2]:match[3]],
+			Offset:   utf8.RuneCountInString(content[:start]),
+			Length:   utf8.RuneCountInString(content[start:end]),
+		})
+	}
+	return mentions
+}
+
+// mentionsJSON selects the mentions of a post (commentID "NULL") or of a
+// comment as a JSON array shaped like Mention.
+func mentionsJSON(postID, commentID string) string {
+	return `COALESCE((SELECT json_agg(json_build_object(
+		'user_id', mu.id, 'username', mu.username, 'offset', m.start_offset, 'length', m.length
+	) ORDER BY m.start_offset)
+	FROM mentions AS m JOIN users AS mu ON mu.id = m.user_id
+	WHERE m.post_id = ` + postID + ` AND m.comment_id IS NOT DISTINCT FROM ` + commentID + `), '[]'::json)`
+}
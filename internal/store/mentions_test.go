@@ -0,0 +1,28 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMentions(t *testing.T) {
+	tests := []struct {
+		content string
+		want    []Mention
+	}{
+		{"hi @alice and @bob_2!", []Mention{
+			{Username: "alice", Offset: 3, Length: 6},
+			{Username: "bob_2", Offset: 14, Length: 6},
+		}},
+		{"@alice", []Mention{{Username: "alice", Offset: 0, Length: 6}}},
+		{"héllo @älice @bob", []Mention{{Username: "bob", Offset: 13, Length: 4}}},
+		{"mail me at bob@example.com", []Mention{}},
+		{"@@alice", []Mention{}},
+	}
+
+	for _, tt := range tests {
+		if got := parseMentions(tt.content); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseMentions(%q) = %+v, want %+v", tt.content, got, tt.want)
+		}
+	}
+}
@@ -8,18 +8,131 @@ import (
 
 func NewMockStore() Storage {
 	return Storage{
-		Users: &MockUserStore{},
+		Posts:       &MockPostStore{},
+		Users:       &MockUserStore{},
+		Comments:    &MockCommentStore{},
+		Bookmarks:   &MockBookmarkStore{},
+		Attachments: &MockAttachmentStore{},
+		Search:      &MockSearchStore{},
+		Reactions:   &MockReactionStore{},
+		Mentions:    &MockMentionStore{},
+		Trending:    &MockTrendingStore{},
 	}
 }
 
+type MockPostStore struct {
+}
+
+func (s *MockPostStore) Create(context.Context, *Post) error {
+	return nil
+}
+func (s *MockPostStore) Repost(ctx context.Context, userID, postID int64) (*Post, error) {
+	return &Post{UserID: userID, RepostedPostID: &postID}, nil
+}
+func (s *MockPostStore) Unrepost(ctx context.Context, userID, postID int64) error {
+	return nil
+}
+func (s *MockPostStore) CanView(ctx context.Context, post *Post, viewerID int64) (bool, error) {
+	return true, nil
+}
+func (s *MockPostStore) GetAllPosts(ctx context.Context, viewerID int64, q PaginatedPostsQuery, fn func(*PostWithMetadata) error) error {
+	return nil
+}
+func (s *MockPostStore) GetPostByID(ctx context.Context, id int64) (*Post, error) {
+	return nil, ErrNotFound
+}
+func (s *MockPostStore) UpdatePost(ctx context.Context, post *Post, editorID int64) error {
+	return nil
+}
+func (s *MockPostStore) DeletePost(ctx context.Context, postID, deletedBy int64) error {
+	return nil
+}
+func (s *MockPostStore) RestorePost(ctx context.Context, postID int64) error {
+	return nil
+}
+func (s *MockPostStore) PurgeDeleted(ctx context.Context, before time.Time) (int64, []string, error) {
+	return 0, nil, nil
+}
+func (s *MockPostStore) PublishScheduled(ctx context.Context, limit int) (int64, error) {
+	return 0, nil
+}
+func (s *MockPostStore) GetDrafts(ctx context.Context, userID int64, p PaginationQuery) ([]PostWithMetadata, error) {
+	return []PostWithMetadata{}, nil
+}
+func (s *MockPostStore) Pin(ctx context.Context, post *Post, limit int) error {
+	return nil
+}
+func (s *MockPostStore) Unpin(ctx context.Context, postID int64) error {
+	return nil
+}
+func (s *MockPostStore) LockComments(ctx context.Context, post *Post, lockedBy int64, reason string) error {
+	post.CommentsLocked = true
+	post.LockedBy = &lockedBy
+	post.LockReason = &reason
+	return nil
+}
+func (s *MockPostStore) UnlockComments(ctx context.Context, post *Post) error {
+	post.CommentsLocked = false
+	post.LockedBy = nil
+	post.LockReason = nil
+	return nil
+}
+func (s *MockPostStore) GetUserPosts(ctx context.Context, userID, viewerID int64, p PaginationQuery) ([]PostWithMetadata, error) {
+	return []PostWithMetadata{}, nil
+}
+func (s *MockPostStore) GetUserFeed(ctx context.Context, userID int64, fq PaginatedFeedQuery) ([]PostWithMetadata, error) {
+	return []PostWithMetadata{}, nil
+}
+
+type MockCommentStore struct {
+}
+
+func (s *MockCommentStore) GetByPostID(ctx context.Context, postID int64, q CommentsQuery) ([]Comment, error) {
+	return []Comment{}, nil
+}
+func (s *MockCommentStore) Count(ctx context.Context, postID int64) (int, error) {
+	return 0, nil
+}
+func (s *MockCommentStore) GetReplies(ctx context.Context, postID, commentID int64) ([]Comment, error) {
+	return []Comment{}, nil
+}
+func (s *MockCommentStore) GetByID(ctx context.Context, id int64) (*Comment, error) {
+	return nil, ErrNotFound
+}
+func (s *MockCommentStore) Create(context.Context, *Comment) error {
+	return nil
+}
+func (s *MockCommentStore) Update(ctx context.Context, comment *Comment) error {
+	return nil
+}
+func (s *MockCommentStore) Delete(ctx context.Context, commentID, deletedBy int64) error {
+	return nil
+}
+
+type MockBookmarkStore struct {
+}
+
+func (s *MockBookmarkStore) Add(ctx context.Context, userID, postID int64) error {
+	return nil
+}
+func (s *MockBookmarkStore) Remove(ctx context.Context, userID, postID int64) error {
+	return nil
+}
+func (s *MockBookmarkStore) Exists(ctx context.Context, userID, postID int64) (bool, error) {
+	return false, nil
+}
+func (s *MockBookmarkStore) GetByUser(ctx context.Context, userID int64, p PaginationQuery) ([]PostWithMetadata, error) {
+	return []PostWithMetadata{}, nil
+}
+
 type MockUserStore struct {
 }
 
 func (s *MockUserStore) Create(context.Context, *sql.Tx, *User) error {
 	return nil
 }
-func (s *MockUserStore) GetUserByID(context.Context, int64) (*User, error) {
-	return nil, nil
+func (s *MockUserStore) GetUserByID(ctx context.Context, userID int64) (*User, error) {
+	return &User{ID: userID}, nil
 }
 func (s *MockUserStore) UpdateUser(ctx context.Context, user *User) error {
 	return nil
@@ -36,3 +149,53 @@ func (s *MockUserStore) Activate(ctx context.Context, token string) error {
 func (s *MockUserStore) GetByEmail(ctx context.Context, email string) (*User, error) {
 	return nil, nil
 }
+
+type MockSearchStore struct {
+}
+
+func (s *MockSearchStore) Search(ctx context.Context, viewerID int64, sq SearchQuery) ([]SearchResult, error) {
+	return []SearchResult{}, nil
+}
+
+type MockReactionStore struct {
+}
+
+func (s *MockReactionStore) Set(ctx context.Context, reaction *Reaction) error {
+	return nil
+}
+func (s *MockReactionStore) Delete(ctx context.Context, postID, userID int64, reactionType string) error {
+	return nil
+}
+func (s *MockReactionStore) GetSummary(ctx context.Context, postID, viewerID int64) (*ReactionSummary, error) {
+	return &ReactionSummary{Counts: map[string]int{}}, nil
+}
+
+type MockAttachmentStore struct {
+}
+
+func (s *MockAttachmentStore) GetByPostID(ctx context.Context, postID int64) ([]Attachment, error) {
+	return []Attachment{}, nil
+}
+func (s *MockAttachmentStore) GetByID(ctx context.Context, id, viewerID int64) (*Attachment, error) {
+	return nil, ErrNotFound
+}
+
+type MockMentionStore struct {
+}
+
+func (s *MockMentionStore) GetPostsByUser(ctx context.Context, userID int64, p PaginationQuery) ([]PostWithMetadata, error) {
+	return []PostWithMetadata{}, nil
+}
+
+type MockTrendingStore struct {
+}
+
+func (s *MockTrendingStore) Refresh(ctx context.Context, window time.Duration, limit int) error {
+	return nil
+}
+func (s *MockTrendingStore) GetPosts(ctx context.Context, viewerID int64, p PaginationQuery) ([]PostWithMetadata, error) {
+	return []PostWithMetadata{}, nil
+}
+func (s *MockTrendingStore) GetTags(ctx context.Context, p PaginationQuery) ([]TrendingTag, error) {
+	return []TrendingTag{}, nil
+}
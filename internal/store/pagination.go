@@ -1,20 +1,107 @@
 package store
 
 import (
+	"encoding/base64"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/lucianboboc/goBackendEngineering/internal/hashtag"
 )
 
+var ErrInvalidCursor = errors.New("invalid cursor")
+
 type PaginatedFeedQuery struct {
-	Limit  int      `json:"limit" validate:"gte=1,lte=20"`
-	Offset int      `json:"offset" validate:"gte=0"`
-	Sort   string   `json:"sort" validate:"oneof=asc desc"`
-	Tags   []string `json:"tags" validate:"max=5"`
-	Search string   `json:"search" validate:"max=100"`
-	Since  string   `json:"since,omitempty"`
-	Until  string   `json:"until,omitempty"`
+	Limit    int      `json:"limit" validate:"gte=1,lte=20"`
+	Offset   int      `json:"offset" validate:"gte=0"`
+	Cursor   *Cursor  `json:"cursor,omitempty"`
+	Sort     string   `json:"sort" validate:"oneof=asc desc"`
+	Tags     []string `json:"tags" validate:"max=5"`
+	TagMatch string   `json:"tag_match" validate:"oneof=any all"`
+	Search   string   `json:"search" validate:"max=100"`
+	Since    string   `json:"since,omitempty"`
+	Until    string   `json:"until,omitempty"`
+}
+
+// PaginatedPostsQuery filters and pages the explore timeline. Cursors are only
+// meaningful when sorting by newest; the most commented order pages by offset.
+type PaginatedPostsQuery struct {
+	Limit    int      `json:"limit" validate:"gte=1,lte=50"`
+	Offset   int      `json:"offset" validate:"gte=0"`
+	Cursor   *Cursor  `json:"cursor,omitempty"`
+	Sort     string   `json:"sort" validate:"oneof=newest most_commented"`
+	AuthorID int64    `json:"author_id" validate:"gte=0"`
+	Tags     []string `json:"tags" validate:"max=5"`
+	TagMatch string   `json:"tag_match" validate:"oneof=any all"`
+	Search   string   `json:"search" validate:"max=100"`
+	Since    string   `json:"since,omitempty"`
+	Until    string   `json:"until,omitempty"`
+}
+
+type PaginationQuery struct {
+	Limit  int `json:"limit" validate:"gte=1,lte=50"`
+	Offset int `json:"offset" validate:"gte=0"`
+}
+
+// CommentsQuery pages the top level comments of a post, replies come nested
+// in the comment they answer.
+type CommentsQuery struct {
+	Limit  int     `json:"limit" validate:"gte=1,lte=50"`
+	Cursor *Cursor `json:"cursor,omitempty"`
+	Sort   string  `json:"sort" validate:"oneof=newest oldest"`
+}
+
+// TagsQuery looks up tags starting with Prefix for autocompletion.
+type TagsQuery struct {
+	Prefix string `json:"prefix" validate:"required,max=50"`
+	Limit  int    `json:"limit" validate:"gte=1,lte=20"`
+}
+
+type SearchQuery struct {
+	Query  string `json:"q" validate:"required,max=200"`
+	Type   string `json:"type" validate:"oneof=all posts comments"`
+	Limit  int    `json:"limit" validate:"gte=1,lte=50"`
+	Offset int    `json:"offset" validate:"gte=0"`
+}
+
+// Cursor marks a position in a list ordered by (created_at, id). It is
+// handed to clients as an opaque string so that keyset pagination stays
+// stable while new rows are being inserted.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        int64
+}
+
+func (c Cursor) Encode() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "," + strconv.FormatInt(c.ID, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeCursor(s string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	parts := strings.SplitN(string(raw), ",", 2)
+	if len(parts) != 2 {
+		return nil, ErrInvalidCursor
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	id, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &Cursor{CreatedAt: createdAt, ID: id}, nil
 }
 
 func (fq PaginatedFeedQuery) Parse(r *http.Request) (PaginatedFeedQuery, error) {
@@ -38,6 +125,15 @@ func (fq PaginatedFeedQuery) Parse(r *http.Request) (PaginatedFeedQuery, error)
 		fq.Offset = o
 	}
 
+	cursor := qs.Get("cursor")
+	if cursor != "" {
+		c, err := DecodeCursor(cursor)
+		if err != nil {
+			return fq, err
+		}
+		fq.Cursor = c
+	}
+
 	sort := qs.Get("sort")
 	if sort != "" {
 		fq.Sort = sort
@@ -45,7 +141,16 @@ func (fq PaginatedFeedQuery) Parse(r *http.Request) (PaginatedFeedQuery, error)
 
 	tags := qs.Get("tags")
 	if tags != "" {
-		fq.Tags = strings.Split(tags, ",")
+		t, err := parseTags(tags)
+		if err != nil {
+			return fq, err
+		}
+		fq.Tags = t
+	}
+
+	tagMatch := qs.Get("tag_match")
+	if tagMatch != "" {
+		fq.TagMatch = tagMatch
 	}
 
 	search := qs.Get("search")
@@ -55,21 +160,237 @@ func (fq PaginatedFeedQuery) Parse(r *http.Request) (PaginatedFeedQuery, error)
 
 	since := qs.Get("since")
 	if since != "" {
-		fq.Since = parseTime(since)
+		t, err := parseTime(since)
+		if err != nil {
+			return fq, err
+		}
+		fq.Since = t
 	}
 
 	until := qs.Get("until")
 	if until != "" {
-		fq.Since = parseTime(until)
+		t, err := parseTime(until)
+		if err != nil {
+			return fq, err
+		}
+		fq.Until = t
 	}
 
 	return fq, nil
 }
 
-func parseTime(s string) string {
+// parseTime accepts an RFC 3339 timestamp and keeps its offset so the
+// database compares it against created_at in the right time zone.
+func parseTime(s string) (string, error) {
 	t, err := time.Parse(time.RFC3339, s)
 	if err != nil {
-		return ""
+		return "", err
+	}
+	return t.Format(time.RFC3339), nil
+}
+
+func (q PaginatedPostsQuery) Parse(r *http.Request) (PaginatedPostsQuery, error) {
+	qs := r.URL.Query()
+
+	limit := qs.Get("limit")
+	if limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return q, err
+		}
+		q.Limit = l
+	}
+
+	offset := qs.Get("offset")
+	if offset != "" {
+		o, err := strconv.Atoi(offset)
+		if err != nil {
+			return q, err
+		}
+		q.Offset = o
+	}
+
+	cursor := qs.Get("cursor")
+	if cursor != "" {
+		c, err := DecodeCursor(cursor)
+		if err != nil {
+			return q, err
+		}
+		q.Cursor = c
 	}
-	return t.Format(time.DateTime)
+
+	sort := qs.Get("sort")
+	if sort != "" {
+		q.Sort = sort
+	}
+
+	authorID := qs.Get("author_id")
+	if authorID != "" {
+		id, err := strconv.ParseInt(authorID, 10, 64)
+		if err != nil {
+			return q, err
+		}
+		q.AuthorID = id
+	}
+
+	tags := qs.Get("tags")
+	if tags != "" {
+		t, err := parseTags(tags)
+		if err != nil {
+			return q, err
+		}
+		q.Tags = t
+	}
+
+	tagMatch := qs.Get("tag_match")
+	if tagMatch != "" {
+		q.TagMatch = tagMatch
+	}
+
+	search := qs.Get("search")
+	if search != "" {
+		q.Search = search
+	}
+
+	since := qs.Get("since")
+	if since != "" {
+		t, err := parseTime(since)
+		if err != nil {
+			return q, err
+		}
+		q.Since = t
+	}
+
+	until := qs.Get("until")
+	if until != "" {
+		t, err := parseTime(until)
+		if err != nil {
+			return q, err
+		}
+		q.Until = t
+	}
+
+	return q, nil
+}
+
+func (sq SearchQuery) Parse(r *http.Request) (SearchQuery, error) {
+	qs := r.URL.Query()
+
+	sq.Query = strings.TrimSpace(qs.Get("q"))
+
+	searchType := qs.Get("type")
+	if searchType != "" {
+		sq.Type = searchType
+	}
+
+	limit := qs.Get("limit")
+	if limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return sq, err
+		}
+		sq.Limit = l
+	}
+
+	offset := qs.Get("offset")
+	if offset != "" {
+		o, err := strconv.Atoi(offset)
+		if err != nil {
+			return sq, err
+		}
+		sq.Offset = o
+	}
+
+	return sq, nil
+}
+
+func (q PaginationQuery) Parse(r *http.Request) (PaginationQuery, error) {
+	qs := r.URL.Query()
+
+	limit := qs.Get("limit")
+	if limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return q, err
+		}
+		q.Limit = l
+	}
+
+	offset := qs.Get("offset")
+	if offset != "" {
+		o, err := strconv.Atoi(offset)
+		if err != nil {
+			return q, err
+		}
+		q.Offset = o
+	}
+
+	return q, nil
+}
+
+// parseTags splits a comma separated list of tags and normalizes them the way
+// they are stored on posts.
+func parseTags(s string) ([]string, error) {
+	tags := make([]string, 0)
+	for _, t := range strings.Split(s, ",") {
+		tag, ok := hashtag.Normalize(t)
+		if !ok {
+			return nil, fmt.Errorf("invalid tag %q", t)
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+func (q TagsQuery) Parse(r *http.Request) (TagsQuery, error) {
+	qs := r.URL.Query()
+
+	prefix := qs.Get("prefix")
+	if prefix != "" {
+		p, ok := hashtag.Normalize(prefix)
+		if !ok {
+			return q, fmt.Errorf("invalid tag prefix %q", prefix)
+		}
+		q.Prefix = p
+	}
+
+	limit := qs.Get("limit")
+	if limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return q, err
+		}
+		q.Limit = l
+	}
+
+	return q, nil
+}
+
+func (q CommentsQuery) Parse(r *http.Request) (CommentsQuery, error) {
+	qs := r.URL.Query()
+
+	limit := qs.Get("limit")
+	if limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return q, err
+		}
+		q.Limit = l
+	}
+
+	cursor := qs.Get("cursor")
+	if cursor != "" {
+		c, err := DecodeCursor(cursor)
+		if err != nil {
+			return q, err
+		}
+		q.Cursor = c
+	}
+
+	sort := qs.Get("sort")
+	if sort != "" {
+		q.Sort = sort
+	}
+
+	return q, nil
 }
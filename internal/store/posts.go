@@ -3,111 +3,295 @@ package store
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
 
 	"github.com/lib/pq"
+	"github.com/lucianboboc/goBackendEngineering/internal/hashtag"
+	"github.com/lucianboboc/goBackendEngineering/internal/markdown"
+)
+
+// Publishing states of a post. Drafts and scheduled posts are only visible to
+// their author until they are published.
+const (
+	StatusDraft     = "draft"
+	StatusScheduled = "scheduled"
+	StatusPublished = "published"
 )
 
 type Post struct {
-	ID        int64      `json:"id"`
-	Content   string     `json:"content"`
-	Title     string     `json:"title"`
-	UserID    int64      `json:"user_id"`
-	Tags      []string   `json:"tags"`
-	CreatedAt *time.Time `json:"created_at"`
-	UpdatedAt *time.Time `json:"updated_at"`
-	Version   int        `json:"version"`
-	Comments  []Comment  `json:"comments"`
-	User      User       `json:"user"`
+	ID             int64        `json:"id"`
+	Content        string       `json:"content"`
+	ContentHTML    string       `json:"content_html"`
+	Title          string       `json:"title"`
+	UserID         int64        `json:"user_id"`
+	Tags           []string     `json:"tags"`
+	ExplicitTags   []string     `json:"-"`
+	CreatedAt      *time.Time   `json:"created_at"`
+	UpdatedAt      *time.Time   `json:"updated_at"`
+	Version        int          `json:"version"`
+	Comments       []Comment    `json:"comments"`
+	User           User         `json:"user"`
+	IsBookmarked   bool         `json:"is_bookmarked"`
+	RepostedPostID *int64       `json:"reposted_post_id"`
+	QuotedPostID   *int64       `json:"quoted_post_id"`
+	RepostOf       *Post        `json:"repost_of,omitempty"`
+	QuotedPost     *Post        `json:"quoted_post,omitempty"`
+	Attachments    []Attachment `json:"attachments"`
+	Mentions       []Mention    `json:"mentions"`
+	Visibility     string       `json:"visibility"`
+	Status         string       `json:"status"`
+	PublishAt      *time.Time   `json:"publish_at"`
+	PinnedAt       *time.Time   `json:"pinned_at"`
+	ViewCount      int64        `json:"view_count"`
+	CommentsLocked bool         `json:"comments_locked"`
+	LockedBy       *int64       `json:"locked_by"`
+	LockReason     *string      `json:"lock_reason"`
 }
 
 type PostWithMetadata struct {
 	Post
-	CommentsCount int `json:"comments_count"`
+	CommentsCount  int            `json:"comments_count"`
+	ReactionCounts map[string]int `json:"reaction_counts"`
+	MyReaction     *string        `json:"my_reaction"`
 }
 
 type PostsStore struct {
 	db *sql.DB
 }
 
+// Create inserts the post together with the metadata of its attachments and
+// the users mentioned in its content. post.Tags holds the tags given
+// explicitly, the #hashtags of the title and content are added to them.
 func (s *PostsStore) Create(ctx context.Context, post *Post) error {
-	query := `INSERT INTO posts (content, title, user_id, tags) 
-	VALUES($1, $2, $3, $4) RETURNING id, created_at, updated_at`
+	attachments := &AttachmentsStore{s.db}
+	mentions := &MentionsStore{s.db}
 
-	err := s.db.QueryRowContext(
-		ctx,
-		query,
-		post.Content,
-		post.Title,
-		post.UserID,
-		pq.Array(post.Tags),
-	).Scan(
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		query := `INSERT INTO posts (content, content_html, title, user_id, tags, explicit_tags, quoted_post_id, visibility, status, publish_at)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id, created_at, updated_at`
+
+		if post.Visibility == "" {
+			post.Visibility = VisibilityPublic
+		}
+		if post.Status == "" {
+			post.Status = StatusPublished
+		}
+
+		post.ContentHTML = markdown.Render(post.Content)
+		post.ExplicitTags = hashtag.Merge(post.Tags)
+		post.mergeTags()
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			post.Content,
+			post.ContentHTML,
+			post.Title,
+			post.UserID,
+			pq.Array(post.Tags),
+			pq.Array(post.ExplicitTags),
+			post.QuotedPostID,
+			post.Visibility,
+			post.Status,
+			post.PublishAt,
+		).Scan(
+			&post.ID,
+			&post.CreatedAt,
+			&post.UpdatedAt,
+		)
+		if err != nil {
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		for i := range post.Attachments {
+			post.Attachments[i].PostID = post.ID
+			if err = attachments.create(ctx, tx, &post.Attachments[i]); err != nil {
+				return err
+			}
+		}
+
+		post.Mentions, err = mentions.replace(ctx, tx, post.ID, nil, post.Content)
+		return err
+	})
+}
+
+// Repost shares the post with the followers of the user. A repost is a post
+// without content of its own that points at the original.
+func (s *PostsStore) Repost(ctx context.Context, userID, postID int64) (*Post, error) {
+	query := `INSERT INTO posts (content, content_html, title, user_id, tags, reposted_post_id)
+	VALUES('', '', '', $1, '{}', $2) RETURNING id, created_at, updated_at`
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+
+	post := &Post{
+		UserID:         userID,
+		Tags:           []string{},
+		RepostedPostID: &postID,
+		Visibility:     VisibilityPublic,
+		Status:         StatusPublished,
+	}
+	err := s.db.QueryRowContext(ctx, query, userID, postID).Scan(
 		&post.ID,
 		&post.CreatedAt,
 		&post.UpdatedAt,
 	)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code {
+			case "23505":
+				return nil, ErrConflict
+			case "23503":
+				return nil, ErrNotFound
+			}
+		}
+		return nil, err
+	}
 
+	return post, nil
+}
+
+func (s *PostsStore) Unrepost(ctx context.Context, userID, postID int64) error {
+	// soft deleted like any other post, the partial unique index lets the user
+	// repost it again
+	query := `UPDATE posts SET deleted_at = NOW(), deleted_by = $1, pinned_at = NULL
+	WHERE user_id = $1 AND reposted_post_id = $2 AND deleted_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, userID, postID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
 	if err != nil {
 		return err
 	}
 
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
 	return nil
 }
 
-func (s *PostsStore) GetAllPosts(ctx context.Context) ([]Post, error) {
-	query := `SELECT id, title, user_id, content, tags, created_at, updated_at, version FROM posts`
+// GetAllPosts walks the explore timeline page described by q and hands every
+// row to fn as soon as it is read, so callers never hold more than they need.
+func (s *PostsStore) GetAllPosts(ctx context.Context, viewerID int64, q PaginatedPostsQuery, fn func(*PostWithMetadata) error) error {
+	orderBy, comparison := "p.created_at DESC, p.id DESC", "<"
+	if q.Sort == "most_commented" {
+		orderBy = "comments_count DESC, p.created_at DESC, p.id DESC"
+	}
+
+	tagOperator := "&&"
+	if q.TagMatch == "all" {
+		tagOperator = "@>"
+	}
+
+	query := fmt.Sprintf(`SELECT %s
+	FROM posts AS p
+	JOIN users AS u ON p.user_id = u.id
+	WHERE
+		p.deleted_at IS NULL
+		AND p.status = 'published'
+		AND p.reposted_post_id IS NULL
+		AND `+visibleTo("p", "$10")+`
+		AND ($3::bigint IS NULL OR p.user_id = $3)
+		AND (p.title ILIKE '%%' || $4 || '%%' OR p.content ILIKE '%%' || $4 || '%%')
+		AND ($5::timestamptz IS NULL OR (p.created_at, p.id) %s ($5, $6))
+		AND ($7::text[] IS NULL OR p.tags %s $7)
+		AND ($8::timestamptz IS NULL OR p.created_at >= $8)
+		AND ($9::timestamptz IS NULL OR p.created_at <= $9)
+	ORDER BY %s
+	LIMIT $1 OFFSET $2`, postWithMetadataColumns("$10"), comparison, tagOperator, orderBy)
 
 	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
 	defer cancel()
 
-	posts := make([]Post, 0)
-	rows, err := s.db.QueryContext(ctx, query)
+	var (
+		offset          = q.Offset
+		cursorCreatedAt *time.Time
+		cursorID        int64
+	)
+	if q.Cursor != nil {
+		offset = 0
+		cursorCreatedAt = &q.Cursor.CreatedAt
+		cursorID = q.Cursor.ID
+	}
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		query,
+		q.Limit,
+		offset,
+		sql.NullInt64{Int64: q.AuthorID, Valid: q.AuthorID != 0},
+		q.Search,
+		cursorCreatedAt,
+		cursorID,
+		pq.Array(q.Tags),
+		sql.NullString{String: q.Since, Valid: q.Since != ""},
+		sql.NullString{String: q.Until, Valid: q.Until != ""},
+		viewerID,
+	)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		var post Post
-		err = rows.Scan(
-			&post.ID,
-			&post.Title,
-			&post.UserID,
-			&post.Content,
-			pq.Array(&post.Tags),
-			&post.CreatedAt,
-			&post.UpdatedAt,
-			&post.Version,
-		)
-		if err != nil {
-			return nil, err
+		var post PostWithMetadata
+		if err = scanPostWithMetadata(rows, &post); err != nil {
+			return err
+		}
+
+		if err = fn(&post); err != nil {
+			return err
 		}
-		posts = append(posts, post)
-	}
-	if err = rows.Err(); err != nil {
-		return nil, err
 	}
-	return posts, nil
+
+	return rows.Err()
 }
 
 func (s *PostsStore) GetPostByID(ctx context.Context, id int64) (*Post, error) {
-	query := `SELECT id, title, user_id, content, tags, created_at, updated_at, version FROM posts WHERE id = $1`
+	query := `SELECT id, title, user_id, content, content_html, tags, explicit_tags, created_at, updated_at, version, reposted_post_id, quoted_post_id, visibility, status, publish_at, pinned_at, view_count,
+	comments_locked, locked_by, lock_reason,
+	` + mentionsJSON("posts.id", "NULL") + `
+	FROM posts WHERE id = $1 AND deleted_at IS NULL`
 
 	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
 	defer cancel()
 
 	var post Post
+	var contentHTML sql.NullString
+	var mentions []byte
 	err := s.db.QueryRowContext(ctx, query, id).Scan(
 		&post.ID,
 		&post.Title,
 		&post.UserID,
 		&post.Content,
+		&contentHTML,
 		pq.Array(&post.Tags),
+		pq.Array(&post.ExplicitTags),
 		&post.CreatedAt,
 		&post.UpdatedAt,
 		&post.Version,
+		&post.RepostedPostID,
+		&post.QuotedPostID,
+		&post.Visibility,
+		&post.Status,
+		&post.PublishAt,
+		&post.PinnedAt,
+		&post.ViewCount,
+		&post.CommentsLocked,
+		&post.LockedBy,
+		&post.LockReason,
+		&mentions,
 	)
 	if err != nil {
 		switch {
@@ -118,28 +302,73 @@ func (s *PostsStore) GetPostByID(ctx context.Context, id int64) (*Post, error) {
 		}
 	}
 
+	post.ContentHTML = renderedContent(contentHTML, post.Content)
+	if err = json.Unmarshal(mentions, &post.Mentions); err != nil {
+		return nil, err
+	}
+
 	return &post, nil
 }
 
-func (s *PostsStore) UpdatePost(ctx context.Context, post *Post) error {
+// UpdatePost saves the post if it is still at post.Version and records the
+// new version in post_revisions within the same transaction.
+func (s *PostsStore) UpdatePost(ctx context.Context, post *Post, editorID int64) error {
+	revisions := &PostRevisionsStore{s.db}
+	mentions := &MentionsStore{s.db}
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		if err := revisions.snapshotPost(ctx, tx, post.ID, post.Version); err != nil {
+			return err
+		}
+
+		if err := s.updatePost(ctx, tx, post); err != nil {
+			return err
+		}
+
+		var err error
+		if post.Mentions, err = mentions.replace(ctx, tx, post.ID, nil, post.Content); err != nil {
+			return err
+		}
+
+		return revisions.create(ctx, tx, post, editorID)
+	})
+}
+
+// mergeTags sets the tags of the post to its explicit tags and the #hashtags
+// of its current title and content, so a hashtag removed by an edit is dropped.
+func (p *Post) mergeTags() {
+	p.Tags = hashtag.MergeStored(p.ExplicitTags, p.Title, p.Content)
+}
+
+func (s *PostsStore) updatePost(ctx context.Context, tx *sql.Tx, post *Post) error {
 	query := `UPDATE posts 
-	SET content = $1, title = $2, version = $3
-	WHERE id = $4
-	AND version = $5
-	RETURNING version`
+	SET content = $1, content_html = $2, title = $3, tags = $4, visibility = $5, version = $6, updated_at = NOW(),
+		-- a draft published by hand goes live now, the timelines follow created_at
+		created_at = CASE WHEN status <> 'published' AND $7::varchar = 'published' THEN NOW() ELSE created_at END,
+		status = $7, publish_at = $8
+	WHERE id = $9
+	AND version = $10
+	RETURNING version, created_at, updated_at`
 
 	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
 	defer cancel()
 
-	err := s.db.QueryRowContext(
+	post.ContentHTML = markdown.Render(post.Content)
+	post.mergeTags()
+	err := tx.QueryRowContext(
 		ctx,
 		query,
 		post.Content,
+		post.ContentHTML,
 		post.Title,
+		pq.Array(post.Tags),
+		post.Visibility,
 		post.Version+1,
+		post.Status,
+		post.PublishAt,
 		post.ID,
 		post.Version,
-	).Scan(&post.Version)
+	).Scan(&post.Version, &post.CreatedAt, &post.UpdatedAt)
 
 	if err != nil {
 		switch {
@@ -152,8 +381,235 @@ func (s *PostsStore) UpdatePost(ctx context.Context, post *Post) error {
 	return nil
 }
 
-func (s *PostsStore) DeletePost(ctx context.Context, postID int64) error {
-	query := `DELETE FROM posts WHERE id = $1`
+// DeletePost hides the post and its comments. The rows stay in place until
+// PurgeDeleted removes them, so RestorePost can bring them back.
+func (s *PostsStore) DeletePost(ctx context.Context, postID, deletedBy int64) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		query := `UPDATE posts SET deleted_at = NOW(), deleted_by = $2, pinned_at = NULL
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING deleted_at`
+
+		ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+		defer cancel()
+
+		var deletedAt time.Time
+		err := tx.QueryRowContext(ctx, query, postID, deletedBy).Scan(&deletedAt)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+
+		// the comments share the post's deleted_at so a restore can tell them
+		// apart from comments removed on their own
+		query = `UPDATE comments SET deleted_at = $2, deleted_by = $3
+		WHERE post_id = $1 AND deleted_at IS NULL`
+
+		_, err = tx.ExecContext(ctx, query, postID, deletedAt, deletedBy)
+		return err
+	})
+}
+
+// RestorePost undoes DeletePost, bringing back the comments that were removed
+// together with the post.
+func (s *PostsStore) RestorePost(ctx context.Context, postID int64) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		query := `UPDATE posts AS p SET deleted_at = NULL, deleted_by = NULL
+		FROM (SELECT id, deleted_at FROM posts WHERE id = $1 FOR UPDATE) AS old
+		WHERE p.id = old.id AND old.deleted_at IS NOT NULL
+		RETURNING old.deleted_at`
+
+		ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+		defer cancel()
+
+		var deletedAt time.Time
+		err := tx.QueryRowContext(ctx, query, postID).Scan(&deletedAt)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+
+		query = `UPDATE comments SET deleted_at = NULL, deleted_by = NULL
+		WHERE post_id = $1 AND deleted_at = $2`
+
+		_, err = tx.ExecContext(ctx, query, postID, deletedAt)
+		return err
+	})
+}
+
+// PurgeDeleted permanently removes the posts and comments deleted before the
+// given time. It returns how many posts were removed and the blob keys of their
+// attachments, which the caller has to delete from the blob store.
+func (s *PostsStore) PurgeDeleted(ctx context.Context, before time.Time) (int64, []string, error) {
+	var purged int64
+	keys := make([]string, 0)
+
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, time.Second*30)
+		defer cancel()
+
+		query := `DELETE FROM comments
+		WHERE deleted_at < $1
+		OR post_id IN (SELECT id FROM posts WHERE deleted_at < $1)`
+
+		if _, err := tx.ExecContext(ctx, query, before); err != nil {
+			return err
+		}
+
+		query = `DELETE FROM attachments
+		WHERE post_id IN (SELECT id FROM posts WHERE deleted_at < $1)
+		RETURNING storage_key`
+
+		rows, err := tx.QueryContext(ctx, query, before)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var key string
+			if err = rows.Scan(&key); err != nil {
+				return err
+			}
+			keys = append(keys, key)
+		}
+		if err = rows.Err(); err != nil {
+			return err
+		}
+
+		query = `DELETE FROM posts WHERE deleted_at < $1`
+
+		res, err := tx.ExecContext(ctx, query, before)
+		if err != nil {
+			return err
+		}
+
+		purged, err = res.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return purged, keys, nil
+}
+
+// PublishScheduled publishes up to limit scheduled posts that are due and
+// returns how many were published. Rows locked by another API instance
+// running the same job are skipped, and the status check in the UPDATE makes
+// sure a post is only ever published once.
+func (s *PostsStore) PublishScheduled(ctx context.Context, limit int) (int64, error) {
+	query := `UPDATE posts SET status = 'published', created_at = publish_at, updated_at = NOW(), version = version + 1
+	WHERE status = 'scheduled' AND id IN (
+		SELECT id FROM posts
+		WHERE status = 'scheduled' AND publish_at <= NOW() AND deleted_at IS NULL
+		ORDER BY publish_at
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	)`
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// GetDrafts returns the drafts and scheduled posts of the user, most recently
+// edited first.
+func (s *PostsStore) GetDrafts(ctx context.Context, userID int64, p PaginationQuery) ([]PostWithMetadata, error) {
+	query := fmt.Sprintf(`SELECT %s
+	FROM posts AS p
+	JOIN users AS u ON p.user_id = u.id
+	WHERE p.user_id = $1 AND p.status <> 'published' AND p.deleted_at IS NULL
+	ORDER BY p.updated_at DESC, p.id DESC
+	LIMIT $2 OFFSET $3`, postWithMetadataColumns("$1"))
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID, p.Limit, p.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := make([]PostWithMetadata, 0)
+	for rows.Next() {
+		var post PostWithMetadata
+		if err = scanPostWithMetadata(rows, &post); err != nil {
+			return nil, err
+		}
+
+		posts = append(posts, post)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+// Pin pins the post to the profile of its author, who can have at most limit
+// pinned posts. Pinning a post that is already pinned does nothing.
+func (s *PostsStore) Pin(ctx context.Context, post *Post, limit int) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+		defer cancel()
+
+		// locking the author serializes concurrent pins so the limit holds
+		_, err := tx.ExecContext(ctx, `SELECT id FROM users WHERE id = $1 FOR UPDATE`, post.UserID)
+		if err != nil {
+			return err
+		}
+
+		query := `SELECT
+			COUNT(*) FILTER (WHERE id <> $2),
+			COUNT(*) FILTER (WHERE id = $2)
+		FROM posts WHERE user_id = $1 AND pinned_at IS NOT NULL AND deleted_at IS NULL`
+
+		var pinned, alreadyPinned int
+		if err = tx.QueryRowContext(ctx, query, post.UserID, post.ID).Scan(&pinned, &alreadyPinned); err != nil {
+			return err
+		}
+		if alreadyPinned > 0 {
+			return nil
+		}
+		if pinned >= limit {
+			return ErrPinLimitReached
+		}
+
+		query = `UPDATE posts SET pinned_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING pinned_at`
+
+		err = tx.QueryRowContext(ctx, query, post.ID).Scan(&post.PinnedAt)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *PostsStore) Unpin(ctx context.Context, postID int64) error {
+	query := `UPDATE posts SET pinned_at = NULL WHERE id = $1 AND pinned_at IS NOT NULL AND deleted_at IS NULL`
 
 	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
 	defer cancel()
@@ -162,36 +618,193 @@ func (s *PostsStore) DeletePost(ctx context.Context, postID int64) error {
 	if err != nil {
 		return err
 	}
-	rows, err := res.RowsAffected()
+
+	rowsAffected, err := res.RowsAffected()
 	if err != nil {
 		return err
 	}
-	if rows == 0 {
+
+	if rowsAffected == 0 {
 		return ErrNotFound
 	}
 	return nil
 }
 
+// LockComments stops new comments on the post, recording who locked them and
+// why. Locking the comments again replaces the reason.
+func (s *PostsStore) LockComments(ctx context.Context, post *Post, lockedBy int64, reason string) error {
+	query := `UPDATE posts SET comments_locked = true, locked_by = $2, lock_reason = $3
+	WHERE id = $1 AND deleted_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, post.ID, lockedBy, reason)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	post.CommentsLocked = true
+	post.LockedBy = &lockedBy
+	post.LockReason = &reason
+	return nil
+}
+
+func (s *PostsStore) UnlockComments(ctx context.Context, post *Post) error {
+	query := `UPDATE posts SET comments_locked = false, locked_by = NULL, lock_reason = NULL
+	WHERE id = $1 AND deleted_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, post.ID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	post.CommentsLocked = false
+	post.LockedBy = nil
+	post.LockReason = nil
+	return nil
+}
+
+// GetUserPosts returns the profile timeline of the user as seen by the viewer:
+// the pinned posts, most recently pinned first, followed by the other posts
+// and reposts, newest first.
+func (s *PostsStore) GetUserPosts(ctx context.Context, userID, viewerID int64, p PaginationQuery) ([]PostWithMetadata, error) {
+	query := fmt.Sprintf(`SELECT %s
+	FROM posts AS p
+	JOIN users AS u ON p.user_id = u.id
+	WHERE p.user_id = $1 AND p.deleted_at IS NULL AND p.status = 'published' AND `+visibleTo("p", "$2")+`
+		AND (p.reposted_post_id IS NULL OR EXISTS (
+			SELECT 1 FROM posts AS op
+			WHERE op.id = p.reposted_post_id AND op.deleted_at IS NULL AND op.status = 'published'
+			AND `+visibleTo("op", "$2")+`
+		))
+	ORDER BY p.pinned_at DESC NULLS LAST, p.created_at DESC, p.id DESC
+	LIMIT $3 OFFSET $4`, postWithMetadataColumns("$2"))
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID, viewerID, p.Limit, p.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := make([]PostWithMetadata, 0)
+	for rows.Next() {
+		var post PostWithMetadata
+		if err = scanPostWithMetadata(rows, &post); err != nil {
+			return nil, err
+		}
+
+		posts = append(posts, post)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
 func (s *PostsStore) GetUserFeed(ctx context.Context, userID int64, fq PaginatedFeedQuery) ([]PostWithMetadata, error) {
-	query := `SELECT p.id, p.user_id, p.title, p.content, p.created_at, p.version, p.tags,
-    u.username, COUNT(c.id) as comments_count
+	// the feed holds the user's own posts and the posts of everyone they follow;
+	// when a cursor is given the page starts right after it (keyset
+	// pagination) and the offset is ignored.
+	// A repost is skipped when its original is already part of the feed or
+	// when a newer repost of the same original is, so each post shows once.
+	// Reposts carry no title, content or tags of their own, the search and
+	// tag filters match them against their original.
+	direction, comparison := "DESC", "<"
+	if fq.Sort == "asc" {
+		direction, comparison = "ASC", ">"
+	}
+
+	// "any" keeps posts sharing at least one tag, "all" keeps posts carrying every tag
+	tagOperator := "&&"
+	if fq.TagMatch == "all" {
+		tagOperator = "@>"
+	}
+
+	query := fmt.Sprintf(`SELECT %[4]s
 	FROM posts AS p
-	LEFT JOIN comments AS c ON c.post_id = p.id
-	LEFT JOIN users AS u ON p.user_id = u.id
-	JOIN followers AS f ON f.follower_id = p.user_id OR p.user_id = $1
+	JOIN users AS u ON p.user_id = u.id
+	LEFT JOIN posts AS o ON o.id = p.reposted_post_id
 	WHERE 
-	    f.user_id = $1 OR p.user_id = $1 AND
-		(p.title ILIKE '%' || $4 || '%' OR p.content ILIKE '%' || $4 || '%')
-	GROUP BY p.id, u.username
-	ORDER BY p.created_at desc
-	LIMIT $2 OFFSET $3`
+	    p.deleted_at IS NULL
+		AND p.status = 'published'
+		AND (p.user_id = $1 OR p.user_id IN (SELECT user_id FROM followers WHERE follower_id = $1))
+		AND `+visibleTo("p", "$1")+`
+		AND (p.reposted_post_id IS NULL OR (
+			EXISTS (
+				SELECT 1 FROM posts AS op
+				WHERE op.id = p.reposted_post_id AND op.deleted_at IS NULL AND op.status = 'published'
+				AND `+visibleTo("op", "$1")+`
+				AND op.user_id <> $1
+				AND op.user_id NOT IN (SELECT user_id FROM followers WHERE follower_id = $1)
+			)
+			AND NOT EXISTS (
+				SELECT 1 FROM posts AS rp
+				WHERE rp.reposted_post_id = p.reposted_post_id AND rp.deleted_at IS NULL
+				AND (rp.user_id = $1 OR rp.user_id IN (SELECT user_id FROM followers WHERE follower_id = $1))
+				AND (rp.created_at, rp.id) > (p.created_at, p.id)
+			)
+		))
+		AND (COALESCE(o.title, p.title) ILIKE '%%' || $4 || '%%' OR COALESCE(o.content, p.content) ILIKE '%%' || $4 || '%%')
+		AND ($5::timestamptz IS NULL OR (p.created_at, p.id) %[2]s ($5, $6))
+		AND ($7::text[] IS NULL OR COALESCE(o.tags, p.tags) %[3]s $7)
+		AND ($8::timestamptz IS NULL OR p.created_at >= $8)
+		AND ($9::timestamptz IS NULL OR p.created_at <= $9)
+	ORDER BY p.created_at %[1]s, p.id %[1]s
+	LIMIT $2 OFFSET $3`, direction, comparison, tagOperator, postWithMetadataColumns("$1"))
 
 	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
 	defer cancel()
 
-	fmt.Printf("feed query: %+v\n", fq)
+	var (
+		offset          = fq.Offset
+		cursorCreatedAt *time.Time
+		cursorID        int64
+	)
+	if fq.Cursor != nil {
+		offset = 0
+		cursorCreatedAt = &fq.Cursor.CreatedAt
+		cursorID = fq.Cursor.ID
+	}
 
-	rows, err := s.db.QueryContext(ctx, query, userID, fq.Limit, fq.Offset, fq.Search)
+	rows, err := s.db.QueryContext(
+		ctx,
+		query,
+		userID,
+		fq.Limit,
+		offset,
+		fq.Search,
+		cursorCreatedAt,
+		cursorID,
+		pq.Array(fq.Tags),
+		sql.NullString{String: fq.Since, Valid: fq.Since != ""},
+		sql.NullString{String: fq.Until, Valid: fq.Until != ""},
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -200,18 +813,7 @@ func (s *PostsStore) GetUserFeed(ctx context.Context, userID int64, fq Paginated
 	results := make([]PostWithMetadata, 0)
 	for rows.Next() {
 		var postWithMetadata PostWithMetadata
-		err := rows.Scan(
-			&postWithMetadata.ID,
-			&postWithMetadata.UserID,
-			&postWithMetadata.Title,
-			&postWithMetadata.Content,
-			&postWithMetadata.CreatedAt,
-			&postWithMetadata.Version,
-			pq.Array(&postWithMetadata.Tags),
-			&postWithMetadata.User.Username,
-			&postWithMetadata.CommentsCount,
-		)
-		if err != nil {
+		if err = scanPostWithMetadata(rows, &postWithMetadata); err != nil {
 			return nil, err
 		}
 
@@ -223,3 +825,126 @@ func (s *PostsStore) GetUserFeed(ctx context.Context, userID int64, fq Paginated
 
 	return results, nil
 }
+
+// postWithMetadataColumns returns the select list read by scanPostWithMetadata.
+// It expects posts aliased as p and their author joined as u; viewer is the
+// placeholder bound to the id of the user reading the posts.
+func postWithMetadataColumns(viewer string) string {
+	return `p.id, p.user_id, p.title, p.content, p.content_html, p.created_at, p.version, p.tags, p.visibility, p.status, p.publish_at, p.pinned_at, p.view_count,
+	p.comments_locked, p.locked_by, p.lock_reason,
+	u.username, (SELECT COUNT(*) FROM comments AS c WHERE c.post_id = p.id AND c.deleted_at IS NULL) AS comments_count,
+	(SELECT jsonb_object_agg(r.type, r.count) FROM (
+		SELECT type, COUNT(*) AS count FROM post_reactions WHERE post_id = p.id GROUP BY type
+	) AS r) AS reaction_counts,
+	(SELECT type FROM post_reactions WHERE post_id = p.id AND user_id = ` + viewer + `) AS my_reaction,
+	EXISTS (SELECT 1 FROM bookmarks WHERE post_id = p.id AND user_id = ` + viewer + `) AS is_bookmarked,
+	p.reposted_post_id, p.quoted_post_id,
+	` + embeddedPostJSON("p.reposted_post_id", viewer) + ` AS repost_of,
+	` + embeddedPostJSON("p.quoted_post_id", viewer) + ` AS quoted_post,
+	(
+		SELECT json_agg(json_build_object(
+			'id', a.id, 'post_id', a.post_id, 'filename', a.filename, 'content_type', a.content_type,
+			'size', a.size, 'checksum', a.checksum, 'created_at', a.created_at
+		) ORDER BY a.id)
+		FROM attachments AS a WHERE a.post_id = p.id
+	) AS attachments,
+	` + mentionsJSON("p.id", "NULL") + ` AS mentions`
+}
+
+// embeddedPostJSON selects the post with the given id and its author as a
+// JSON document shaped like Post, or NULL when it is missing, deleted,
+// unpublished or not visible to the viewer.
+func embeddedPostJSON(id, viewer string) string {
+	return `(SELECT json_build_object(
+		'id', ep.id, 'title', ep.title, 'content', ep.content, 'content_html', ep.content_html, 'user_id', ep.user_id,
+		'tags', ep.tags, 'created_at', ep.created_at, 'updated_at', ep.updated_at, 'version', ep.version,
+		'visibility', ep.visibility, 'user', json_build_object('id', eu.id, 'username', eu.username)
+	) FROM posts AS ep JOIN users AS eu ON eu.id = ep.user_id
+	WHERE ep.id = ` + id + ` AND ep.deleted_at IS NULL AND ep.status = 'published' AND ` + visibleTo("ep", viewer) + `)`
+}
+
+func scanPostWithMetadata(rows *sql.Rows, post *PostWithMetadata) error {
+	var reactionCounts, repostOf, quotedPost, attachments, mentions []byte
+	var contentHTML sql.NullString
+
+	err := rows.Scan(
+		&post.ID,
+		&post.UserID,
+		&post.Title,
+		&post.Content,
+		&contentHTML,
+		&post.CreatedAt,
+		&post.Version,
+		pq.Array(&post.Tags),
+		&post.Visibility,
+		&post.Status,
+		&post.PublishAt,
+		&post.PinnedAt,
+		&post.ViewCount,
+		&post.CommentsLocked,
+		&post.LockedBy,
+		&post.LockReason,
+		&post.User.Username,
+		&post.CommentsCount,
+		&reactionCounts,
+		&post.MyReaction,
+		&post.IsBookmarked,
+		&post.RepostedPostID,
+		&post.QuotedPostID,
+		&repostOf,
+		&quotedPost,
+		&attachments,
+		&mentions,
+	)
+	if err != nil {
+		return err
+	}
+	post.ContentHTML = renderedContent(contentHTML, post.Content)
+
+	post.ReactionCounts = make(map[string]int)
+	if reactionCounts != nil {
+		if err = json.Unmarshal(reactionCounts, &post.ReactionCounts); err != nil {
+			return err
+		}
+	}
+
+	if repostOf != nil {
+		if err = json.Unmarshal(repostOf, &post.RepostOf); err != nil {
+			return err
+		}
+		renderEmbeddedContent(post.RepostOf)
+	}
+
+	if quotedPost != nil {
+		if err = json.Unmarshal(quotedPost, &post.QuotedPost); err != nil {
+			return err
+		}
+		renderEmbeddedContent(post.QuotedPost)
+	}
+
+	post.Attachments = make([]Attachment, 0)
+	if attachments != nil {
+		if err = json.Unmarshal(attachments, &post.Attachments); err != nil {
+			return err
+		}
+	}
+
+	return json.Unmarshal(mentions, &post.Mentions)
+}
+
+// renderedContent returns the stored HTML of the content, rendering it when
+// the row was written before content_html existed.
+func renderedContent(stored sql.NullString, content string) string {
+	if stored.Valid {
+		return stored.String
+	}
+	return markdown.Render(content)
+}
+
+// renderEmbeddedContent fills in the HTML of a post decoded from
+// embeddedPostJSON, where a missing content_html decodes as "".
+func renderEmbeddedContent(post *Post) {
+	if post.ContentHTML == "" && post.Content != "" {
+		post.ContentHTML = markdown.Render(post.Content)
+	}
+}
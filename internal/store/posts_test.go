@@ -0,0 +1,30 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPostMergeTags(t *testing.T) {
+	post := &Post{ExplicitTags: []string{"go"}, Title: "title", Content: "learning #foo"}
+
+	post.mergeTags()
+	if want := []string{"go", "foo"}; !reflect.DeepEqual(post.Tags, want) {
+		t.Fatalf("expected tags %v, got %v", want, post.Tags)
+	}
+
+	post.Content = "learning"
+	post.mergeTags()
+	if want := []string{"go"}; !reflect.DeepEqual(post.Tags, want) {
+		t.Errorf("expected foo to be dropped once removed from the content, got %v", post.Tags)
+	}
+}
+
+func TestPostMergeTagsKeepsLegacyTags(t *testing.T) {
+	post := &Post{ExplicitTags: []string{"c++"}, Title: "title", Content: "learning #go"}
+
+	post.mergeTags()
+	if want := []string{"c++", "go"}; !reflect.DeepEqual(post.Tags, want) {
+		t.Errorf("expected the legacy tag to be kept, got %v", post.Tags)
+	}
+}
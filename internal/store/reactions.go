@@ -0,0 +1,108 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// ReactionTypes lists the reactions a user can leave on a post.
+var ReactionTypes = []string{"like", "love", "laugh", "wow", "sad", "angry"}
+
+type Reaction struct {
+	PostID    int64      `json:"post_id"`
+	UserID    int64      `json:"user_id"`
+	Type      string     `json:"type"`
+	CreatedAt *time.Time `json:"created_at"`
+}
+
+type ReactionSummary struct {
+	Counts     map[string]int `json:"counts"`
+	Total      int            `json:"total"`
+	MyReaction *string        `json:"my_reaction"`
+}
+
+type ReactionsStore struct {
+	db *sql.DB
+}
+
+// Set leaves the reaction on the post, replacing any other reaction the user
+// had on it since each user has a single reaction per post.
+func (s *ReactionsStore) Set(ctx context.Context, reaction *Reaction) error {
+	query := `INSERT INTO post_reactions (post_id, user_id, type)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (post_id, user_id) DO UPDATE SET type = EXCLUDED.type, created_at = NOW()
+	RETURNING created_at`
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+
+	return s.db.QueryRowContext(
+		ctx,
+		query,
+		reaction.PostID,
+		reaction.UserID,
+		reaction.Type,
+	).Scan(&reaction.CreatedAt)
+}
+
+func (s *ReactionsStore) Delete(ctx context.Context, postID, userID int64, reactionType string) error {
+	query := `DELETE FROM post_reactions WHERE post_id = $1 AND user_id = $2 AND type = $3`
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, postID, userID, reactionType)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *ReactionsStore) GetSummary(ctx context.Context, postID, viewerID int64) (*ReactionSummary, error) {
+	query := `SELECT type, COUNT(*), BOOL_OR(user_id = $2)
+	FROM post_reactions
+	WHERE post_id = $1
+	GROUP BY type`
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, postID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summary := &ReactionSummary{Counts: make(map[string]int)}
+	for rows.Next() {
+		var (
+			reactionType string
+			count        int
+			mine         bool
+		)
+		if err = rows.Scan(&reactionType, &count, &mine); err != nil {
+			return nil, err
+		}
+
+		summary.Counts[reactionType] = count
+		summary.Total += count
+		if mine {
+			summary.MyReaction = &reactionType
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return summary, nil
+}
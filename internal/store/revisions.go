@@ -0,0 +1,134 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// PostRevision is the state of a post at a given version. Revisions are
+// written by PostsStore.UpdatePost, so the latest one matches the post itself.
+type PostRevision struct {
+	PostID    int64      `json:"post_id"`
+	Version   int        `json:"version"`
+	Title     string     `json:"title"`
+	Content   string     `json:"content"`
+	Tags      []string   `json:"tags"`
+	EditedBy  *int64     `json:"edited_by"`
+	CreatedAt *time.Time `json:"created_at"`
+}
+
+type PostRevisionsStore struct {
+	db *sql.DB
+}
+
+func (s *PostRevisionsStore) GetByPostID(ctx context.Context, postID int64) ([]PostRevision, error) {
+	query := `SELECT post_id, version, title, content, tags, edited_by, created_at
+	FROM post_revisions
+	WHERE post_id = $1
+	ORDER BY version DESC`
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := make([]PostRevision, 0)
+	for rows.Next() {
+		var revision PostRevision
+		err = rows.Scan(
+			&revision.PostID,
+			&revision.Version,
+			&revision.Title,
+			&revision.Content,
+			pq.Array(&revision.Tags),
+			&revision.EditedBy,
+			&revision.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		revisions = append(revisions, revision)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+func (s *PostRevisionsStore) GetByVersion(ctx context.Context, postID int64, version int) (*PostRevision, error) {
+	query := `SELECT post_id, version, title, content, tags, edited_by, created_at
+	FROM post_revisions
+	WHERE post_id = $1 AND version = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+
+	var revision PostRevision
+	err := s.db.QueryRowContext(ctx, query, postID, version).Scan(
+		&revision.PostID,
+		&revision.Version,
+		&revision.Title,
+		&revision.Content,
+		pq.Array(&revision.Tags),
+		&revision.EditedBy,
+		&revision.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &revision, nil
+}
+
+// snapshotPost makes sure the given version of a post is recorded before it is
+// replaced. Posts edited for the first time (or written before revisions
+// existed) get their current state saved here; the original author is only
+// known for version 0.
+func (s *PostRevisionsStore) snapshotPost(ctx context.Context, tx *sql.Tx, postID int64, version int) error {
+	query := `INSERT INTO post_revisions (post_id, version, title, content, tags, edited_by, created_at)
+	SELECT id, version, title, content, tags, CASE WHEN version = 0 THEN user_id END, updated_at
+	FROM posts
+	WHERE id = $1 AND version = $2
+	ON CONFLICT (post_id, version) DO NOTHING`
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+
+	_, err := tx.ExecContext(ctx, query, postID, version)
+	return err
+}
+
+func (s *PostRevisionsStore) create(ctx context.Context, tx *sql.Tx, post *Post, editorID int64) error {
+	query := `INSERT INTO post_revisions (post_id, version, title, content, tags, edited_by)
+	VALUES ($1, $2, $3, $4, $5, $6)`
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+
+	_, err := tx.ExecContext(
+		ctx,
+		query,
+		post.ID,
+		post.Version,
+		post.Title,
+		post.Content,
+		pq.Array(post.Tags),
+		editorID,
+	)
+	return err
+}
@@ -0,0 +1,108 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"html"
+	"strings"
+	"time"
+)
+
+type SearchResult struct {
+	Type      string     `json:"type"`
+	ID        int64      `json:"id"`
+	PostID    int64      `json:"post_id"`
+	UserID    int64      `json:"user_id"`
+	Title     string     `json:"title,omitempty"`
+	Snippet   string     `json:"snippet"`
+	Rank      float64    `json:"rank"`
+	CreatedAt *time.Time `json:"created_at"`
+}
+
+// The matches of a snippet are delimited by control characters that cannot
+// be mistaken for markup. The snippet is HTML escaped before they are turned
+// into <mark> tags, so the content of posts and comments is never rendered.
+const (
+	highlightStart = "\x02"
+	highlightStop  = "\x03"
+
+	headlineOptions = "StartSel=" + highlightStart + ", StopSel=" + highlightStop + ", MaxFragments=2, MaxWords=30, MinWords=10"
+)
+
+var highlighter = strings.NewReplacer(highlightStart, "<mark>", highlightStop, "</mark>")
+
+type SearchStore struct {
+	db *sql.DB
+}
+
+// Search matches posts and comments against a websearch style query
+// ("quoted phrases", -excluded, or) and returns them by relevance. Snippets
+// are only built for the rows on the requested page since ts_headline has to
+// re-parse the whole document. Snippets are HTML with the matches in <mark>
+// tags. Only posts visible to the viewer, and the comments on them, are
+// searched.
+func (s *SearchStore) Search(ctx context.Context, viewerID int64, sq SearchQuery) ([]SearchResult, error) {
+	query := `WITH q AS (SELECT websearch_to_tsquery('english', $1) AS query)
+	SELECT r.type, r.id, r.post_id, r.user_id, r.title,
+		ts_headline('english', translate(r.content, $6, ''), q.query, $7),
+		r.rank, r.created_at
+	FROM (
+		SELECT * FROM (
+			SELECT 'post' AS type, p.id, p.id AS post_id, p.user_id, p.title, p.content,
+				ts_rank(p.search_vector, q.query) AS rank, p.created_at
+			FROM posts AS p, q
+			WHERE $2 IN ('all', 'posts') AND p.deleted_at IS NULL AND p.status = 'published' AND p.search_vector @@ q.query
+				AND ` + visibleTo("p", "$5") + `
+			UNION ALL
+			SELECT 'comment' AS type, c.id, c.post_id, c.user_id, '' AS title, c.content,
+				ts_rank(c.search_vector, q.query) AS rank, c.created_at
+			FROM comments AS c JOIN posts AS p ON p.id = c.post_id, q
+			WHERE $2 IN ('all', 'comments') AND c.deleted_at IS NULL AND c.search_vector @@ q.query
+				AND p.deleted_at IS NULL AND p.status = 'published' AND ` + visibleTo("p", "$5") + `
+		) AS matches
+		ORDER BY rank DESC, created_at DESC, id DESC
+		LIMIT $3 OFFSET $4
+	) AS r, q
+	ORDER BY r.rank DESC, r.created_at DESC, r.id DESC`
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, sq.Query, sq.Type, sq.Limit, sq.Offset, viewerID, highlightStart+highlightStop, headlineOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]SearchResult, 0)
+	for rows.Next() {
+		var result SearchResult
+		err = rows.Scan(
+			&result.Type,
+			&result.ID,
+			&result.PostID,
+			&result.UserID,
+			&result.Title,
+			&result.Snippet,
+			&result.Rank,
+			&result.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		result.Snippet = highlightSnippet(result.Snippet)
+
+		results = append(results, result)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// highlightSnippet escapes a snippet built by ts_headline with headlineOptions
+// and marks its matches.
+func highlightSnippet(snippet string) string {
+	return highlighter.Replace(html.EscapeString(snippet))
+}
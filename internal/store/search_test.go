@@ -0,0 +1,20 @@
+package store
+
+import "testing"
+
+func TestHighlightSnippet(t *testing.T) {
+	tests := []struct {
+		snippet string
+		want    string
+	}{
+		{"learning \x02go\x03 today", "learning <mark>go</mark> today"},
+		{"<script>alert(1)</script> \x02go\x03", "&lt;script&gt;alert(1)&lt;/script&gt; <mark>go</mark>"},
+		{"<img src=x onerror=\"alert(1)\"> \x02go\x03", "&lt;img src=x onerror=&#34;alert(1)&#34;&gt; <mark>go</mark>"},
+	}
+
+	for _, tt := range tests {
+		if got := highlightSnippet(tt.snippet); got != tt.want {
+			t.Errorf("highlightSnippet(%q) = %q, want %q", tt.snippet, got, tt.want)
+		}
+	}
+}
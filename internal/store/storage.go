@@ -10,15 +10,29 @@ import (
 var (
 	ErrNotFound = errors.New("resource not found")
 	ErrConflict = errors.New("resource already exists")
+
+	ErrPinLimitReached = errors.New("pinned posts limit reached")
 )
 
 type PostsStorage interface {
 	Create(context.Context, *Post) error
-	GetAllPosts(ctx context.Context) ([]Post, error)
+	Repost(ctx context.Context, userID, postID int64) (*Post, error)
+	Unrepost(ctx context.Context, userID, postID int64) error
+	GetAllPosts(ctx context.Context, viewerID int64, q PaginatedPostsQuery, fn func(*PostWithMetadata) error) error
 	GetPostByID(ctx context.Context, id int64) (*Post, error)
-	UpdatePost(ctx context.Context, post *Post) error
-	DeletePost(ctx context.Context, postID int64) error
+	CanView(ctx context.Context, post *Post, viewerID int64) (bool, error)
+	UpdatePost(ctx context.Context, post *Post, editorID int64) error
+	DeletePost(ctx context.Context, postID, deletedBy int64) error
+	RestorePost(ctx context.Context, postID int64) error
+	PurgeDeleted(ctx context.Context, before time.Time) (int64, []string, error)
 	GetUserFeed(ctx context.Context, userID int64, fq PaginatedFeedQuery) ([]PostWithMetadata, error)
+	PublishScheduled(ctx context.Context, limit int) (int64, error)
+	GetDrafts(ctx context.Context, userID int64, p PaginationQuery) ([]PostWithMetadata, error)
+	Pin(ctx context.Context, post *Post, limit int) error
+	Unpin(ctx context.Context, postID int64) error
+	LockComments(ctx context.Context, post *Post, lockedBy int64, reason string) error
+	UnlockComments(ctx context.Context, post *Post) error
+	GetUserPosts(ctx context.Context, userID, viewerID int64, p PaginationQuery) ([]PostWithMetadata, error)
 }
 
 type UsersStorage interface {
@@ -32,8 +46,13 @@ type UsersStorage interface {
 }
 
 type CommentsStorage interface {
-	GetByPostID(ctx context.Context, postID int64) ([]Comment, error)
+	GetByPostID(ctx context.Context, postID int64, q CommentsQuery) ([]Comment, error)
+	Count(ctx context.Context, postID int64) (int, error)
+	GetReplies(ctx context.Context, postID, commentID int64) ([]Comment, error)
+	GetByID(ctx context.Context, id int64) (*Comment, error)
 	Create(context.Context, *Comment) error
+	Update(ctx context.Context, comment *Comment) error
+	Delete(ctx context.Context, commentID, deletedBy int64) error
 }
 
 type FollowersStorage interface {
@@ -45,21 +64,86 @@ type RolesStorage interface {
 	GetByName(ctx context.Context, role string) (*Role, error)
 }
 
+type PostRevisionsStorage interface {
+	GetByPostID(ctx context.Context, postID int64) ([]PostRevision, error)
+	GetByVersion(ctx context.Context, postID int64, version int) (*PostRevision, error)
+}
+
+type ReactionsStorage interface {
+	Set(ctx context.Context, reaction *Reaction) error
+	Delete(ctx context.Context, postID, userID int64, reactionType string) error
+	GetSummary(ctx context.Context, postID, viewerID int64) (*ReactionSummary, error)
+}
+
+type BookmarksStorage interface {
+	Add(ctx context.Context, userID, postID int64) error
+	Remove(ctx context.Context, userID, postID int64) error
+	Exists(ctx context.Context, userID, postID int64) (bool, error)
+	GetByUser(ctx context.Context, userID int64, p PaginationQuery) ([]PostWithMetadata, error)
+}
+
+type AttachmentsStorage interface {
+	GetByPostID(ctx context.Context, postID int64) ([]Attachment, error)
+	GetByID(ctx context.Context, id, viewerID int64) (*Attachment, error)
+}
+
+type MentionsStorage interface {
+	GetPostsByUser(ctx context.Context, userID int64, p PaginationQuery) ([]PostWithMetadata, error)
+}
+
+type TagsStorage interface {
+	GetByName(ctx context.Context, name string) (*Tag, error)
+	Autocomplete(ctx context.Context, q TagsQuery) ([]Tag, error)
+}
+
+type TrendingStorage interface {
+	Refresh(ctx context.Context, window time.Duration, limit int) error
+	GetPosts(ctx context.Context, viewerID int64, p PaginationQuery) ([]PostWithMetadata, error)
+	GetTags(ctx context.Context, p PaginationQuery) ([]TrendingTag, error)
+}
+
+type ViewsStorage interface {
+	Add(ctx context.Context, counts []ViewCount) error
+	GetDaily(ctx context.Context, postID int64, days int) ([]ViewCount, error)
+}
+
+type SearchStorage interface {
+	Search(ctx context.Context, viewerID int64, sq SearchQuery) ([]SearchResult, error)
+}
+
 type Storage struct {
-	Posts     PostsStorage
-	Users     UsersStorage
-	Comments  CommentsStorage
-	Followers FollowersStorage
-	Roles     RolesStorage
+	Posts       PostsStorage
+	Users       UsersStorage
+	Comments    CommentsStorage
+	Followers   FollowersStorage
+	Roles       RolesStorage
+	Search      SearchStorage
+	Revisions   PostRevisionsStorage
+	Reactions   ReactionsStorage
+	Bookmarks   BookmarksStorage
+	Attachments AttachmentsStorage
+	Mentions    MentionsStorage
+	Tags        TagsStorage
+	Trending    TrendingStorage
+	Views       ViewsStorage
 }
 
 func NewPostgresStorage(db *sql.DB) Storage {
 	return Storage{
-		Posts:     &PostsStore{db},
-		Users:     &UsersStore{db},
-		Comments:  &CommentsStore{db},
-		Followers: &FollowersStore{db},
-		Roles:     &RolesStore{db},
+		Posts:       &PostsStore{db},
+		Users:       &UsersStore{db},
+		Comments:    &CommentsStore{db},
+		Followers:   &FollowersStore{db},
+		Roles:       &RolesStore{db},
+		Search:      &SearchStore{db},
+		Revisions:   &PostRevisionsStore{db},
+		Reactions:   &ReactionsStore{db},
+		Bookmarks:   &BookmarksStore{db},
+		Attachments: &AttachmentsStore{db},
+		Mentions:    &MentionsStore{db},
+		Tags:        &TagsStore{db},
+		Trending:    &TrendingStore{db},
+		Views:       &ViewsStore{db},
 	}
 }
 
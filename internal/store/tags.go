@@ -0,0 +1,78 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Tag counts the published, public posts carrying a tag. The counts are kept
+// up to date by a trigger on posts, see the count_public_post_tags migration.
+type Tag struct {
+	Name       string     `json:"name"`
+	UsageCount int        `json:"usage_count"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+type TagsStore struct {
+	db *sql.DB
+}
+
+func (s *TagsStore) GetByName(ctx context.Context, name string) (*Tag, error) {
+	query := `SELECT name, usage_count, last_used_at FROM tags WHERE name = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+
+	var tag Tag
+	err := s.db.QueryRowContext(ctx, query, name).Scan(&tag.Name, &tag.UsageCount, &tag.LastUsedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &tag, nil
+}
+
+// Autocomplete returns the tags in use starting with the prefix, most used
+// first.
+func (s *TagsStore) Autocomplete(ctx context.Context, q TagsQuery) ([]Tag, error) {
+	query := `SELECT name, usage_count, last_used_at FROM tags
+	WHERE name LIKE $1 AND usage_count > 0
+	ORDER BY usage_count DESC, name
+	LIMIT $2`
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+
+	// "_" is a wildcard for LIKE but a valid tag character. The pattern is
+	// built here so the planner sees a plain prefix and uses the
+	// text_pattern_ops index.
+	pattern := strings.NewReplacer(`\`, `\\`, `_`, `\_`, `%`, `\%`).Replace(q.Prefix) + "%"
+
+	rows, err := s.db.QueryContext(ctx, query, pattern, q.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := make([]Tag, 0)
+	for rows.Next() {
+		var tag Tag
+		if err = rows.Scan(&tag.Name, &tag.UsageCount, &tag.LastUsedAt); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
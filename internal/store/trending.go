@@ -0,0 +1,166 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// trendingLockID is the advisory lock held while the trending tables are
+// rebuilt, so only one API instance refreshes them at a time.
+const trendingLockID = 190_001
+
+// followerGrowthWeight is how much a new follower of the author counts
+// compared to a new comment on the post.
+const followerGrowthWeight = 0.5
+
+// TrendingTag is a tag ranked by the scores of the trending posts carrying it.
+type TrendingTag struct {
+	Name       string     `json:"name"`
+	Score      float64    `json:"score"`
+	PostCount  int        `json:"post_count"`
+	ComputedAt *time.Time `json:"computed_at"`
+}
+
+type TrendingStore struct {
+	db *sql.DB
+}
+
+// Refresh recomputes the trending posts and tags from the activity of the
+// last window. Every comment on a post and every new follower of its author
+// adds to the score of the post, decaying by half every quarter of the
+// window. At most limit posts and limit tags are kept.
+//
+// The tables are rebuilt in a single transaction with DELETE rather than
+// TRUNCATE, so readers keep seeing the previous ranking until it commits. When
+// another instance is already refreshing, Refresh returns without doing
+// anything.
+func (s *TrendingStore) Refresh(ctx context.Context, window time.Duration, limit int) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, time.Second*30)
+		defer cancel()
+
+		var locked bool
+		err := tx.QueryRowContext(ctx, `SELECT pg_try_advisory_xact_lock($1)`, trendingLockID).Scan(&locked)
+		if err != nil || !locked {
+			return err
+		}
+
+		if _, err = tx.ExecContext(ctx, `DELETE FROM trending_posts`); err != nil {
+			return err
+		}
+
+		query := `WITH comment_activity AS (
+			SELECT post_id, SUM(EXP(-LN(2) * EXTRACT(EPOCH FROM NOW() - created_at) / $2)) AS score
+			FROM comments
+			WHERE deleted_at IS NULL AND created_at >= NOW() - make_interval(secs => $1)
+			GROUP BY post_id
+		), follower_growth AS (
+			SELECT user_id, SUM(EXP(-LN(2) * EXTRACT(EPOCH FROM NOW() - created_at) / $2)) AS score
+			FROM followers
+			WHERE created_at >= NOW() - make_interval(secs => $1)
+			GROUP BY user_id
+		)
+		INSERT INTO trending_posts (post_id, score)
+		SELECT p.id, COALESCE(ca.score, 0) + $3 * COALESCE(fg.score, 0) AS score
+		FROM posts AS p
+		LEFT JOIN comment_activity AS ca ON ca.post_id = p.id
+		LEFT JOIN follower_growth AS fg ON fg.user_id = p.user_id
+		WHERE p.deleted_at IS NULL AND p.status = 'published' AND p.visibility = 'public'
+			AND p.reposted_post_id IS NULL
+			AND (ca.post_id IS NOT NULL OR (fg.user_id IS NOT NULL AND p.created_at >= NOW() - make_interval(secs => $1)))
+		ORDER BY score DESC, p.id DESC
+		LIMIT $4`
+
+		seconds := window.Seconds()
+		_, err = tx.ExecContext(ctx, query, seconds, seconds/4, followerGrowthWeight, limit)
+		if err != nil {
+			return err
+		}
+
+		if _, err = tx.ExecContext(ctx, `DELETE FROM trending_tags`); err != nil {
+			return err
+		}
+
+		query = `INSERT INTO trending_tags (name, score, post_count)
+		SELECT tag, SUM(tp.score), COUNT(*)
+		FROM trending_posts AS tp
+		JOIN posts AS p ON p.id = tp.post_id
+		CROSS JOIN LATERAL unnest(p.tags) AS tag
+		GROUP BY tag
+		ORDER BY SUM(tp.score) DESC, tag
+		LIMIT $1`
+
+		_, err = tx.ExecContext(ctx, query, limit)
+		return err
+	})
+}
+
+// GetPosts returns the trending posts visible to the viewer, highest score
+// first, as of the last refresh.
+func (s *TrendingStore) GetPosts(ctx context.Context, viewerID int64, p PaginationQuery) ([]PostWithMetadata, error) {
+	query := fmt.Sprintf(`SELECT %s
+	FROM trending_posts AS tp
+	JOIN posts AS p ON p.id = tp.post_id
+	JOIN users AS u ON p.user_id = u.id
+	WHERE p.deleted_at IS NULL AND p.status = 'published' AND `+visibleTo("p", "$1")+`
+	ORDER BY tp.score DESC, tp.post_id DESC
+	LIMIT $2 OFFSET $3`, postWithMetadataColumns("$1"))
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, viewerID, p.Limit, p.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := make([]PostWithMetadata, 0)
+	for rows.Next() {
+		var post PostWithMetadata
+		if err = scanPostWithMetadata(rows, &post); err != nil {
+			return nil, err
+		}
+
+		posts = append(posts, post)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+// GetTags returns the trending tags, highest score first, as of the last
+// refresh.
+func (s *TrendingStore) GetTags(ctx context.Context, p PaginationQuery) ([]TrendingTag, error) {
+	query := `SELECT name, score, post_count, computed_at FROM trending_tags
+	ORDER BY score DESC, name
+	LIMIT $1 OFFSET $2`
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, p.Limit, p.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := make([]TrendingTag, 0)
+	for rows.Next() {
+		var tag TrendingTag
+		if err = rows.Scan(&tag.Name, &tag.Score, &tag.PostCount, &tag.ComputedAt); err != nil {
+			return nil, err
+		}
+
+		tags = append(tags, tag)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
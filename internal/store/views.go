@@ -0,0 +1,97 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ViewCount is the number of views a post got on a day (UTC).
+type ViewCount struct {
+	PostID int64     `json:"-"`
+	Day    time.Time `json:"day"`
+	Views  int64     `json:"views"`
+}
+
+type ViewsStore struct {
+	db *sql.DB
+}
+
+// Add adds the counts to the daily views and to the view_count of the posts.
+// Counts of posts that no longer exist are dropped.
+func (s *ViewsStore) Add(ctx context.Context, counts []ViewCount) error {
+	postIDs := make([]int64, len(counts))
+	days := make([]string, len(counts))
+	views := make([]int64, len(counts))
+	for i, c := range counts {
+		postIDs[i] = c.PostID
+		days[i] = c.Day.Format(time.DateOnly)
+		views[i] = c.Views
+	}
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+		defer cancel()
+
+		query := `INSERT INTO post_views_daily (post_id, day, views)
+		SELECT v.post_id, v.day, v.views
+		FROM unnest($1::bigint[], $2::date[], $3::bigint[]) AS v (post_id, day, views)
+		JOIN posts AS p ON p.id = v.post_id
+		ON CONFLICT (post_id, day) DO UPDATE SET views = post_views_daily.views + EXCLUDED.views`
+
+		_, err := tx.ExecContext(ctx, query, pq.Array(postIDs), pq.Array(days), pq.Array(views))
+		if err != nil {
+			return err
+		}
+
+		query = `UPDATE posts SET view_count = view_count + v.views
+		FROM (
+			SELECT post_id, SUM(views) AS views
+			FROM unnest($1::bigint[], $2::bigint[]) AS u (post_id, views)
+			GROUP BY post_id
+		) AS v
+		WHERE posts.id = v.post_id`
+
+		_, err = tx.ExecContext(ctx, query, pq.Array(postIDs), pq.Array(views))
+		return err
+	})
+}
+
+// GetDaily returns the views of the post for each of the last days, oldest
+// first, with the days without views counted as zero.
+func (s *ViewsStore) GetDaily(ctx context.Context, postID int64, days int) ([]ViewCount, error) {
+	query := `SELECT d.day::date, COALESCE(v.views, 0)
+	FROM generate_series(
+		(NOW() AT TIME ZONE 'UTC')::date - ($2::int - 1),
+		(NOW() AT TIME ZONE 'UTC')::date,
+		interval '1 day'
+	) AS d (day)
+	LEFT JOIN post_views_daily AS v ON v.post_id = $1 AND v.day = d.day::date
+	ORDER BY d.day`
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, postID, days)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make([]ViewCount, 0, days)
+	for rows.Next() {
+		c := ViewCount{PostID: postID}
+		if err = rows.Scan(&c.Day, &c.Views); err != nil {
+			return nil, err
+		}
+
+		counts = append(counts, c)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,51 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// Visibility levels of a post. The author always sees their own posts.
+const (
+	VisibilityPublic    = "public"
+	VisibilityFollowers = "followers"
+	VisibilityMentioned = "mentioned"
+	VisibilityPrivate   = "private"
+)
+
+// visibleTo is the SQL condition for the post aliased p being visible to the
+// viewer placeholder: the viewer's own posts, and among published posts the
+// public ones, those of followed users shared with followers and those
+// mentioning the viewer shared with the mentioned users.
+func visibleTo(p, viewer string) string {
+	return `(` + p + `.user_id = ` + viewer + ` OR (` + p + `.status = 'published' AND (
+		` + p + `.visibility = 'public'
+		OR (` + p + `.visibility = 'followers' AND EXISTS (
+			SELECT 1 FROM followers WHERE user_id = ` + p + `.user_id AND follower_id = ` + viewer + `
+		))
+		OR (` + p + `.visibility = 'mentioned' AND EXISTS (
+			SELECT 1 FROM mentions WHERE post_id = ` + p + `.id AND comment_id IS NULL AND user_id = ` + viewer + `
+		)))))`
+}
+
+// CanView reports whether the viewer may see the post. Callers answer with
+// 404 when they may not, so the existence of the post is not revealed.
+func (s *PostsStore) CanView(ctx context.Context, post *Post, viewerID int64) (bool, error) {
+	switch {
+	case post.UserID == viewerID:
+		return true, nil
+	case post.Status != StatusPublished, post.Visibility == VisibilityPrivate:
+		return false, nil
+	case post.Visibility == VisibilityPublic:
+		return true, nil
+	}
+
+	query := `SELECT EXISTS (SELECT 1 FROM posts AS p WHERE p.id = $1 AND ` + visibleTo("p", "$2") + `)`
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+
+	var visible bool
+	err := s.db.QueryRowContext(ctx, query, post.ID, viewerID).Scan(&visible)
+	return visible, err
+}
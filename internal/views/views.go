@@ -0,0 +1,97 @@
+// Package views counts post views in memory so that reading a post does not
+// turn into a database write. The counts are written in batches by Flush.
+package views
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/lucianboboc/goBackendEngineering/internal/store"
+)
+
+type impression struct {
+	postID   int64
+	viewerID int64
+}
+
+type day struct {
+	postID int64
+	day    time.Time
+}
+
+// Counter records the views of posts. A viewer seeing the same post again
+// within the window is not counted twice. The window is tracked per API
+// instance, so a viewer whose requests land on several instances may be
+// counted once by each of them.
+type Counter struct {
+	mu      sync.Mutex
+	window  time.Duration
+	seen    map[impression]time.Time
+	pending map[day]int64
+	now     func() time.Time
+}
+
+func NewCounter(window time.Duration) *Counter {
+	return &Counter{
+		window:  window,
+		seen:    make(map[impression]time.Time),
+		pending: make(map[day]int64),
+		now:     time.Now,
+	}
+}
+
+// Record counts a view of the post by the viewer unless the viewer already
+// saw it within the window.
+func (c *Counter) Record(postID, viewerID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	key := impression{postID, viewerID}
+	if expires, ok := c.seen[key]; ok && now.Before(expires) {
+		return
+	}
+	c.seen[key] = now.Add(c.window)
+
+	c.pending[day{postID, now.UTC().Truncate(24 * time.Hour)}]++
+}
+
+// Flush writes the views counted since the last flush with a single call to
+// views.Add and forgets the expired impressions. When the write fails the
+// counts are kept for the next flush.
+func (c *Counter) Flush(ctx context.Context, views store.ViewsStorage) (int64, error) {
+	c.mu.Lock()
+	pending := c.pending
+	c.pending = make(map[day]int64)
+
+	now := c.now()
+	for key, expires := range c.seen {
+		if !now.Before(expires) {
+			delete(c.seen, key)
+		}
+	}
+	c.mu.Unlock()
+
+	if len(pending) == 0 {
+		return 0, nil
+	}
+
+	var total int64
+	counts := make([]store.ViewCount, 0, len(pending))
+	for key, n := range pending {
+		counts = append(counts, store.ViewCount{PostID: key.postID, Day: key.day, Views: n})
+		total += n
+	}
+
+	if err := views.Add(ctx, counts); err != nil {
+		c.mu.Lock()
+		for key, n := range pending {
+			c.pending[key] += n
+		}
+		c.mu.Unlock()
+		return 0, err
+	}
+
+	return total, nil
+}
@@ -0,0 +1,70 @@
+package views
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lucianboboc/goBackendEngineering/internal/store"
+)
+
+type fakeViewsStore struct {
+	store.ViewsStorage
+	counts []store.ViewCount
+	err    error
+}
+
+func (s *fakeViewsStore) Add(ctx context.Context, counts []store.ViewCount) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.counts = append(s.counts, counts...)
+	return nil
+}
+
+func TestCounter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	c := NewCounter(time.Hour)
+	c.now = func() time.Time { return now }
+
+	views := &fakeViewsStore{}
+
+	t.Run("it should count a viewer once per window", func(t *testing.T) {
+		c.Record(1, 10)
+		c.Record(1, 10)
+		c.Record(1, 11)
+		now = now.Add(time.Hour)
+		c.Record(1, 10)
+
+		total, err := c.Flush(context.Background(), views)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total != 3 {
+			t.Errorf("expected 3 views, got %d", total)
+		}
+		if len(views.counts) != 1 || views.counts[0].Views != 3 {
+			t.Errorf("expected a single count of 3 views, got %+v", views.counts)
+		}
+	})
+
+	t.Run("it should keep the counts when the write fails", func(t *testing.T) {
+		views.counts = nil
+		c.Record(2, 10)
+
+		views.err = errors.New("database is down")
+		if _, err := c.Flush(context.Background(), views); err == nil {
+			t.Fatal("expected the flush to fail")
+		}
+
+		views.err = nil
+		total, err := c.Flush(context.Background(), views)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total != 1 || len(views.counts) != 1 || views.counts[0].PostID != 2 {
+			t.Errorf("expected the view of post 2 to be written, got %+v", views.counts)
+		}
+	})
+}